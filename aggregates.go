@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// aggregator derives metrics from the readings of the background scrapes,
+// which are served alongside the cached readings of the target.
+type aggregator interface {
+	recorder
+	Metrics(c collector.Collector, target string, now time.Time) []prometheus.Metric
+}
+
+var (
+	windowMinFamily = collector.NewFamily("wut_temperature_min", "Minimum temperature reading from WUT sensor within the aggregation window", "room", "sensor")
+	windowMaxFamily = collector.NewFamily("wut_temperature_max", "Maximum temperature reading from WUT sensor within the aggregation window", "room", "sensor")
+	windowAvgFamily = collector.NewFamily("wut_temperature_avg", "Average temperature reading from WUT sensor within the aggregation window", "room", "sensor")
+)
+
+// windowAggregates exports the minimum, maximum and average of the
+// readings of every sensor within a sliding window. Scraping the targets
+// more often than Prometheus scrapes the exporter thus still captures short
+// spikes.
+type windowAggregates struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	sensors map[string]map[string][]collector.Reading
+}
+
+func newWindowAggregates(window time.Duration) *windowAggregates {
+	return &windowAggregates{window: window, sensors: make(map[string]map[string][]collector.Reading)}
+}
+
+// Record adds the readings of the target.
+func (a *windowAggregates) Record(target string, readings []collector.Reading) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sensors, ok := a.sensors[target]
+	if !ok {
+		sensors = make(map[string][]collector.Reading)
+		a.sensors[target] = sensors
+	}
+	for _, reading := range readings {
+		sensors[reading.Sensor] = a.prune(append(sensors[reading.Sensor], reading), reading.Timestamp)
+	}
+}
+
+// prune drops the readings that left the window ending now.
+func (a *windowAggregates) prune(readings []collector.Reading, now time.Time) []collector.Reading {
+	start := 0
+	for start < len(readings) && now.Sub(readings[start].Timestamp) > a.window {
+		start++
+	}
+	return readings[start:]
+}
+
+// Metrics returns the aggregates of all sensors of the target over the
+// window ending now and drops readings that left the window.
+func (a *windowAggregates) Metrics(c collector.Collector, target string, now time.Time) []prometheus.Metric {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var metrics []prometheus.Metric
+	for sensor, readings := range a.sensors[target] {
+		readings = a.prune(readings, now)
+		a.sensors[target][sensor] = readings
+		if len(readings) == 0 {
+			delete(a.sensors[target], sensor)
+			continue
+		}
+
+		lowest, highest, sum := readings[0].Value, readings[0].Value, 0.0
+		for _, reading := range readings {
+			lowest = min(lowest, reading.Value)
+			highest = max(highest, reading.Value)
+			sum += reading.Value
+		}
+		room := c.RoomLabel()
+		metrics = append(metrics,
+			prometheus.MustNewConstMetric(c.Desc(windowMinFamily), prometheus.GaugeValue, lowest, room, sensor),
+			prometheus.MustNewConstMetric(c.Desc(windowMaxFamily), prometheus.GaugeValue, highest, room, sensor),
+			prometheus.MustNewConstMetric(c.Desc(windowAvgFamily), prometheus.GaugeValue, sum/float64(len(readings)), room, sensor),
+		)
+	}
+	return metrics
+}
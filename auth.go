@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// requireAdminToken only passes requests to the handler that carry the
+// configured admin token or a valid OIDC token as bearer token.
+func (s *configStore) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		config := s.Get()
+		token, oidc := config.AdminToken, config.OIDC
+		if token == "" && oidc.Issuer == "" {
+			writeError(w, http.StatusForbidden, apiError{Code: errorCodeForbidden, Message: "Administrative endpoints are disabled", Hint: "set admin_token or oidc in the configuration"})
+			return
+		}
+
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok && oidc.Issuer != "" {
+			err := adminOIDC.Verify(r.Context(), oidc, provided)
+			if err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			requestLogger(r.Context(), s.logger).Warn("Rejected OIDC token", zap.Error(err))
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="wut-temperature-exporter"`)
+		writeError(w, http.StatusUnauthorized, apiError{Code: errorCodeUnauthorized, Message: "Unauthorized", Hint: "pass admin_token or an OIDC token as bearer token"})
+	})
+}
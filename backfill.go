@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+// loggerTimeLayouts are the timestamp formats used in the data logger
+// exports of the different firmware versions.
+var loggerTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"02.01.2006 15:04:05",
+	"01/02/2006 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+// backfillFlags are the flags of the backfill command.
+type backfillFlags struct {
+	targetName     *string
+	remoteWriteURL *string
+	loggerPath     *string
+	from           *time.Duration
+	extraLabels    *map[string]string
+	timeout        *time.Duration
+	batchSize      *int
+}
+
+// newBackfillFlags defines the flags of the backfill command on the flag set.
+func newBackfillFlags(flags *pflag.FlagSet) backfillFlags {
+	f := backfillFlags{
+		targetName:     flags.String("target", "", "Room or IP address of the configured target to backfill"),
+		remoteWriteURL: flags.String("remote-write-url", "", "Prometheus remote write endpoint, e.g. http://prometheus:9090/api/v1/write"),
+		loggerPath:     flags.String("logger-path", "/logger.csv", "HTTP path of the CSV export of the device's data logger"),
+		from:           flags.Duration("since", 0, "Only backfill readings newer than this duration, 0 for the complete history"),
+		extraLabels:    flags.StringToString("label", nil, "Additional label added to all series, e.g. --label job=wut (repeatable)"),
+		timeout:        flags.Duration("timeout", 30*time.Second, "Timeout of the HTTP requests"),
+		batchSize:      flags.Int("batch-size", 10000, "Maximum number of samples per remote write request"),
+	}
+	addPublicKeyFlag(flags)
+	return f
+}
+
+// runBackfill downloads the measurement history stored in the data logger
+// of a device and writes it to Prometheus via remote write.
+func runBackfill(args []string) int {
+	flags := pflag.NewFlagSet("backfill", pflag.ContinueOnError)
+	f := newBackfillFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *f.targetName == "" || *f.remoteWriteURL == "" {
+		fmt.Fprintln(os.Stderr, "--target and --remote-write-url are required")
+		return 2
+	}
+	if *f.batchSize <= 0 {
+		fmt.Fprintln(os.Stderr, "--batch-size must be positive")
+		return 2
+	}
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	setupConfig()
+	config, err := loadConfig()
+	if err != nil {
+		logger.Error("No valid configuration found", zap.Error(err))
+		return 1
+	}
+	target, ok := config.findTarget(*f.targetName)
+	if !ok {
+		logger.Error("No target found", zap.String("target", *f.targetName))
+		return 1
+	}
+
+	client := &http.Client{Timeout: *f.timeout}
+	url := "http://" + collector.HTTPHost(target.IP) + *f.loggerPath
+	resp, err := client.Get(url)
+	if err != nil {
+		logger.Error("Error downloading data logger contents", zap.String("url", url), zap.Error(err))
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		logger.Error("Error downloading data logger contents", zap.String("url", url), zap.String("status", resp.Status))
+		return 1
+	}
+
+	history, err := parseLoggerCSV(resp.Body, config.ErrorTokens)
+	if err != nil {
+		logger.Error("Error parsing data logger contents", zap.String("url", url), zap.Error(err))
+		return 1
+	}
+
+	c := config.collector(target, logger)
+	deviceUnit, unit, err := c.HistoryUnits(context.Background())
+	if err != nil {
+		logger.Error("Error reading device unit", zap.String("ip", target.IP), zap.Error(err))
+		return 1
+	}
+	var since time.Time
+	if *f.from > 0 {
+		since = time.Now().Add(-*f.from)
+	}
+	series := historySeries(c, history, since, deviceUnit, unit, *f.extraLabels)
+	samples := 0
+	for _, ts := range series {
+		samples += len(ts.Samples)
+	}
+
+	for _, batch := range batches(series, *f.batchSize) {
+		if err := remoteWrite(client, *f.remoteWriteURL, batch); err != nil {
+			logger.Error("Error writing history", zap.String("url", *f.remoteWriteURL), zap.Error(err))
+			return 1
+		}
+	}
+	logger.Info("Backfilled data logger history", zap.String("target", target.Name()), zap.Int("series", len(series)), zap.Int("samples", samples))
+	return 0
+}
+
+// historySeries converts the data logger history newer than since to the
+// series of a scrape, named and converted according to the options of the
+// collector.
+func historySeries(c collector.Collector, history map[string][]sample, since time.Time, deviceUnit, unit int, extraLabels map[string]string) []timeSeries {
+	var series []timeSeries
+	index := make(map[string]int)
+	for _, sensor := range slices.Sorted(maps.Keys(history)) {
+		for _, s := range history[sensor] {
+			if !s.Timestamp.After(since) {
+				continue
+			}
+			reading, ok := c.HistoryReading(sensor, s.Value, deviceUnit, unit, s.Timestamp)
+			if !ok {
+				continue
+			}
+			for _, named := range c.Samples(reading) {
+				key := named.Name + "\xff" + sensor
+				i, ok := index[key]
+				if !ok {
+					labels := map[string]string{"__name__": named.Name}
+					maps.Copy(labels, extraLabels)
+					maps.Copy(labels, named.Labels)
+					i = len(series)
+					index[key] = i
+					series = append(series, timeSeries{Labels: labels})
+				}
+				series[i].Samples = append(series[i].Samples, sample{Value: named.Value, Timestamp: s.Timestamp})
+			}
+		}
+	}
+	return series
+}
+
+// batches splits the series into remote write requests of at most size
+// samples, splitting series exceeding it.
+func batches(series []timeSeries, size int) [][]timeSeries {
+	var result [][]timeSeries
+	var batch []timeSeries
+	samples := 0
+	for _, ts := range series {
+		for len(ts.Samples) > 0 {
+			n := min(len(ts.Samples), size-samples)
+			batch = append(batch, timeSeries{Labels: ts.Labels, Samples: ts.Samples[:n]})
+			ts.Samples = ts.Samples[n:]
+			samples += n
+			if samples == size {
+				result = append(result, batch)
+				batch, samples = nil, 0
+			}
+		}
+	}
+	if len(batch) > 0 {
+		result = append(result, batch)
+	}
+	return result
+}
+
+// parseLoggerCSV parses a data logger export. The first row names the
+// columns, the first column (or the first two for separate date and time
+// columns) holds the timestamp and every further column one sensor.
+// Timestamps are interpreted in the local time zone of the exporter.
+// Placeholders of channels without a probe are skipped.
+func parseLoggerCSV(r io.Reader, errorTokens []string) (map[string][]sample, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	reader.FieldsPerRecord = -1
+	if strings.Count(string(content), ";") > strings.Count(string(content), ",") {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("no readings found")
+	}
+
+	header := records[0]
+	timeColumns := 1
+	if len(records[1]) > 1 {
+		if _, err := parseLoggerTime(records[1][0] + " " + records[1][1]); err == nil {
+			timeColumns = 2
+		}
+	}
+
+	history := make(map[string][]sample)
+	for _, record := range records[1:] {
+		if len(record) <= timeColumns {
+			continue
+		}
+		timestamp, err := parseLoggerTime(strings.Join(record[:timeColumns], " "))
+		if err != nil {
+			return nil, err
+		}
+		for i := timeColumns; i < len(record) && i < len(header); i++ {
+			raw := record[i]
+			if collector.IsPlaceholder(raw, errorTokens) {
+				continue
+			}
+			value, err := collector.ParseValue(raw)
+			if err != nil {
+				continue
+			}
+			sensor := strings.TrimSpace(header[i])
+			history[sensor] = append(history[sensor], sample{Value: value, Timestamp: timestamp})
+		}
+	}
+	return history, nil
+}
+
+func parseLoggerTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range loggerTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown timestamp format %q", value)
+}
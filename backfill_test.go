@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestHistorySeries(t *testing.T) {
+	now := time.Now()
+	history := map[string][]sample{"Rack 1": {{Value: 68, Timestamp: now.Add(-time.Minute)}, {Value: 77, Timestamp: now}}}
+	config := config{Options: wutconfig.DefaultOptions()}
+	config.MetricNames = wutconfig.MetricNamesUnit
+	c := config.collector(wutconfig.Target{IP: "192.0.2.1", Room: "Server"}, zap.NewNop())
+
+	// The device records Fahrenheit, which normalize_unit converts.
+	series := historySeries(c, history, time.Time{}, collector.UnitFahrenheit, collector.UnitCelsius, map[string]string{"job": "wut"})
+	if len(series) != 1 {
+		t.Fatalf("expected one series, got %d", len(series))
+	}
+	labels := series[0].Labels
+	if labels["__name__"] != "wut_temperature_celsius" || labels["room"] != "server" || labels["sensor"] != "Rack 1" || labels["job"] != "wut" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	if samples := series[0].Samples; len(samples) != 2 || samples[0].Value != 20 || samples[1].Value != 25 {
+		t.Errorf("unexpected samples %v", samples)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	series := []timeSeries{
+		{Labels: map[string]string{"sensor": "1"}, Samples: make([]sample, 5)},
+		{Labels: map[string]string{"sensor": "2"}, Samples: make([]sample, 2)},
+	}
+	var sizes []int
+	for _, batch := range batches(series, 3) {
+		n := 0
+		for _, ts := range batch {
+			n += len(ts.Samples)
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("unexpected batch sizes %v", sizes)
+	}
+}
+
+func TestParseLoggerCSV(t *testing.T) {
+	csv := "Time;Rack;Door\n2026-01-02 03:04:05;1.021,5 °C;--\n"
+	history, err := parseLoggerCSV(strings.NewReader(csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if samples := history["Rack"]; len(samples) != 1 || samples[0].Value != 1021.5 {
+		t.Errorf("unexpected samples of Rack %v", samples)
+	}
+	if _, ok := history["Door"]; ok {
+		t.Error("expected the placeholder of Door to be skipped")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// probeLimiter bounds the number of live probes scraping devices at the
+// same time. Probes beyond the limit wait for a free slot up to a bounded
+// queue length; further probes are rejected with 503 right away rather than
+// piling up goroutines that would all time out.
+type probeLimiter struct {
+	store   *configStore
+	slots   chan struct{}
+	queued  atomic.Int64
+	maxWait int64
+}
+
+// newProbeLimiter returns the limiter of the configuration, or nil if live
+// probes are unlimited.
+func newProbeLimiter(store *configStore) *probeLimiter {
+	config := store.Get()
+	if config.MaxConcurrentProbes <= 0 {
+		return nil
+	}
+	return &probeLimiter{
+		store:   store,
+		slots:   make(chan struct{}, config.MaxConcurrentProbes),
+		maxWait: int64(config.MaxQueuedProbes),
+	}
+}
+
+// handler passes requests to next once a slot is free. A nil limiter
+// passes all requests.
+func (l *probeLimiter) handler(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			if l.queued.Add(1) > l.maxWait {
+				l.queued.Add(-1)
+				l.reject(w)
+				return
+			}
+			probeQueueDepth.Inc()
+			select {
+			case l.slots <- struct{}{}:
+				l.queued.Add(-1)
+				probeQueueDepth.Dec()
+			case <-r.Context().Done():
+				l.queued.Add(-1)
+				probeQueueDepth.Dec()
+				l.reject(w)
+				return
+			}
+		}
+		defer func() { <-l.slots }()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reject responds with 503 and asks the client to retry after the probe
+// timeout, by when the running probes have finished.
+func (l *probeLimiter) reject(w http.ResponseWriter) {
+	probesRejected.Inc()
+	retry := max(1, int(math.Ceil(l.store.Get().ProbeTimeout.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	writeError(w, http.StatusServiceUnavailable, apiError{Code: errorCodeOverloaded, Message: "Too many concurrent probes", Hint: "retry after " + (time.Duration(retry) * time.Second).String()})
+}
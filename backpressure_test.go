@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestProbeLimiterReject(t *testing.T) {
+	store := newConfigStore(config{MaxConcurrentProbes: 1, ProbeTimeout: 9 * time.Second}, zap.NewNop())
+	running, release := make(chan struct{}), make(chan struct{})
+	handler := newProbeLimiter(store).handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(running)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/?target=server", nil))
+	}()
+	<-running
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?target=server", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "9" {
+		t.Errorf("expected 503 with Retry-After 9, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	close(release)
+	<-done
+}
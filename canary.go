@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// canaryStatus is the response of the deep health check.
+type canaryStatus struct {
+	Target   string  `json:"target"`
+	Healthy  bool    `json:"healthy"`
+	Duration float64 `json:"duration_seconds"`
+	Sensors  int     `json:"sensors"`
+	Error    string  `json:"error,omitempty"`
+	// Reason is the class of the error, see collector.Reason.
+	Reason string `json:"reason,omitempty"`
+}
+
+// deepHealthHandler probes the configured canary target via SNMP on every
+// request. It responds with 503 if the probe fails, so that load balancers
+// notice a broken SNMP path even though the exporter itself is up.
+func deepHealthHandler(store *configStore, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r.Context(), fallback)
+		config := store.Get()
+		if config.CanaryTarget == "" {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeNotConfigured, Message: "No canary target configured", Hint: "set canary_target in the configuration"})
+			return
+		}
+		target, ok := config.findTarget(config.CanaryTarget)
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Canary target not found", Target: config.CanaryTarget})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), config.CanaryTimeout)
+		defer cancel()
+		start := time.Now()
+		result := config.collector(target, logger).Scrape(ctx)
+
+		status := canaryStatus{
+			Target:   target.Name(),
+			Healthy:  result.Err == nil,
+			Duration: time.Since(start).Seconds(),
+			Sensors:  len(result.Readings),
+		}
+		if result.Err != nil {
+			status.Error = result.Err.Error()
+			status.Reason = collector.Reason(result.Err)
+			logger.Warn("Canary probe failed", zap.String("target", target.Name()), zap.String("reason", status.Reason), zap.Error(result.Err))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
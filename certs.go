@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// certCheckInterval is the interval the certificate files of TLS listeners
+// are checked for changes.
+const certCheckInterval = 10 * time.Second
+
+// certReloader serves the certificate of a TLS listener and reloads it when
+// the files change or on SIGHUP, so that renewed certificates are picked up
+// without a restart. The files are polled rather than watched, as renewals
+// often replace them via symlinks.
+type certReloader struct {
+	certFile, keyFile string
+	logger            *zap.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+// newCertReloader loads the certificate of the listener.
+func newCertReloader(config *ListenerTLS, logger *zap.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: config.CertFile, keyFile: config.KeyFile, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the certificate again. On failure the current certificate
+// is kept.
+func (r *certReloader) Reload() error {
+	modified, err := r.modTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("error loading certificate: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.modified = &cert, modified
+	return nil
+}
+
+// modTime returns the time the certificate or key file was last modified.
+func (r *certReloader) modTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("error reading certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Watch reloads the certificate whenever its files change or SIGHUP is
+// received until the context is cancelled.
+func (r *certReloader) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(certCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			modified, err := r.modTime()
+			r.mu.RLock()
+			unchanged := err == nil && modified.Equal(r.modified)
+			r.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+		}
+		if err := r.Reload(); err != nil {
+			r.logger.Error("Error reloading TLS certificate, keeping the current one", zap.String("cert_file", r.certFile), zap.Error(err))
+			continue
+		}
+		r.logger.Info("Reloaded TLS certificate", zap.String("cert_file", r.certFile))
+	}
+}
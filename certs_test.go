@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// writeCert writes a self-signed certificate for the common name and its
+// key to the files.
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	config := &ListenerTLS{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	writeCert(t, config.CertFile, config.KeyFile, "old")
+	certs, err := newCertReloader(config, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeCert(t, config.CertFile, config.KeyFile, "renewed")
+	// A broken key keeps the current certificate.
+	os.WriteFile(config.KeyFile, []byte("broken"), 0o600)
+	if err := certs.Reload(); err == nil {
+		t.Fatal("expected an error loading the broken key")
+	}
+	writeCert(t, config.CertFile, config.KeyFile, "renewed")
+	if err := certs.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := certs.GetCertificate(nil)
+	if leaf, _ := x509.ParseCertificate(cert.Certificate[0]); leaf.Subject.CommonName != "renewed" {
+		t.Errorf("expected the renewed certificate, got %s", leaf.Subject.CommonName)
+	}
+}
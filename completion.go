@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// program is the name of the binary in completions and man pages.
+const program = "wut-temperature-exporter"
+
+// commandFlags returns the flags of the subcommand, or of the exporter
+// itself for the empty name.
+func commandFlags(name string) *pflag.FlagSet {
+	if name == "" {
+		flags := pflag.NewFlagSet(program, pflag.ContinueOnError)
+		newRootFlags(flags)
+		return flags
+	}
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	if define := commands[name].flags; define != nil {
+		define(flags)
+	}
+	return flags
+}
+
+// visibleFlags returns the flags of the subcommand that are not hidden.
+func visibleFlags(name string) []*pflag.Flag {
+	var result []*pflag.Flag
+	commandFlags(name).VisitAll(func(flag *pflag.Flag) {
+		if !flag.Hidden {
+			result = append(result, flag)
+		}
+	})
+	return result
+}
+
+// takesValue reports whether the flag requires an argument.
+func takesValue(flag *pflag.Flag) bool {
+	return flag.Value.Type() != "bool" && flag.NoOptDefVal == ""
+}
+
+func init() {
+	commands["completion"] = command{run: runCompletion, summary: "Print a shell completion script", args: "bash|zsh|fish", words: []string{"bash", "zsh", "fish"}}
+	commands["man"] = command{run: runMan, flags: func(f *pflag.FlagSet) { newManFlags(f) }, summary: "Write the man pages of the exporter and its commands"}
+}
+
+// runCompletion prints the completion script of a shell, generated from the
+// commands and their flags.
+func runCompletion(args []string) int {
+	flags := pflag.NewFlagSet("completion", pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: "+program+" completion bash|zsh|fish")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	generators := map[string]func(io.Writer){"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}
+	generate, ok := generators[flags.Arg(0)]
+	if flags.NArg() != 1 || !ok {
+		flags.Usage()
+		return 2
+	}
+	generate(os.Stdout)
+	return 0
+}
+
+func commandNames() []string {
+	return slices.Sorted(maps.Keys(commands))
+}
+
+// bashCompletion completes flags after a dash, the commands or fixed words
+// otherwise, and leaves flag values and files to the default completion.
+func bashCompletion(w io.Writer) {
+	fmt.Fprintf(w, "# bash completion for %s, generated by \"%[1]s completion bash\".\n", program)
+	fmt.Fprintln(w, "_wut_temperature_exporter() {")
+	fmt.Fprintln(w, "\tlocal cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} command= word flags words values")
+	fmt.Fprintln(w, "\tfor word in \"${COMP_WORDS[@]:1:COMP_CWORD-1}\"; do")
+	fmt.Fprintln(w, "\t\tcase $word in")
+	fmt.Fprintf(w, "\t\t%s) command=$word; break ;;\n", strings.Join(commandNames(), "|"))
+	fmt.Fprintln(w, "\t\tesac")
+	fmt.Fprintln(w, "\tdone")
+	fmt.Fprintln(w, "\tcase $command in")
+	for _, name := range append(commandNames(), "") {
+		var flags, values []string
+		for _, flag := range visibleFlags(name) {
+			names := []string{"--" + flag.Name}
+			if flag.Shorthand != "" {
+				names = append(names, "-"+flag.Shorthand)
+			}
+			flags = append(flags, names...)
+			if takesValue(flag) {
+				values = append(values, names...)
+			}
+		}
+		words := commands[name].words
+		pattern := name
+		if name == "" {
+			words, pattern = commandNames(), "*"
+		}
+		fmt.Fprintf(w, "\t%s) flags=%q words=%q values=%q ;;\n", pattern, strings.Join(flags, " "), strings.Join(words, " "), strings.Join(values, " "))
+	}
+	fmt.Fprintln(w, "\tesac")
+	fmt.Fprintln(w, "\tif [[ \" $values \" == *\" $prev \"* ]]; then")
+	fmt.Fprintln(w, "\t\treturn")
+	fmt.Fprintln(w, "\telif [[ $cur == -* ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\telif [[ -n $words ]]; then")
+	fmt.Fprintln(w, "\t\tCOMPREPLY=($(compgen -W \"$words\" -- \"$cur\"))")
+	fmt.Fprintln(w, "\tfi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintf(w, "complete -o default -F _wut_temperature_exporter %s\n", program)
+}
+
+// zshCompletion describes every command with _arguments.
+func zshCompletion(w io.Writer) {
+	fmt.Fprintf(w, "#compdef %s\n", program)
+	fmt.Fprintf(w, "# zsh completion for %s, generated by \"%[1]s completion zsh\".\n", program)
+	fmt.Fprintln(w, "_wut_temperature_exporter() {")
+	fmt.Fprintln(w, "\tif (( CURRENT > 2 )); then")
+	fmt.Fprintln(w, "\t\tcase $words[2] in")
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "\t\t%s)\n", name)
+		fmt.Fprintln(w, "\t\t\tshift words")
+		fmt.Fprintln(w, "\t\t\t(( CURRENT-- ))")
+		specs := zshFlags(name)
+		if words := commands[name].words; len(words) > 0 {
+			specs = append(specs, zshQuote("1:"+commands[name].args+":("+strings.Join(words, " ")+")"))
+		} else if commands[name].args != "" {
+			specs = append(specs, zshQuote("*:file:_files"))
+		}
+		fmt.Fprintf(w, "\t\t\t_arguments -s %s\n", strings.Join(specs, " "))
+		fmt.Fprintln(w, "\t\t\treturn ;;")
+	}
+	fmt.Fprintln(w, "\t\tesac")
+	fmt.Fprintln(w, "\tfi")
+	var described []string
+	for _, name := range commandNames() {
+		described = append(described, zshEscape(name)+`\:`+zshEscape(`"`+commands[name].summary+`"`))
+	}
+	specs := append(zshFlags(""), zshQuote("1:command:(("+strings.Join(described, " ")+"))"))
+	fmt.Fprintf(w, "\t_arguments -s %s\n", strings.Join(specs, " "))
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, `_wut_temperature_exporter "$@"`)
+}
+
+// zshFlags returns the _arguments specs of the flags of the command.
+func zshFlags(name string) []string {
+	var specs []string
+	for _, flag := range visibleFlags(name) {
+		suffix := "[" + zshEscape(flag.Usage) + "]"
+		if takesValue(flag) {
+			suffix = "=" + suffix + ":" + flag.Name + ":"
+		}
+		specs = append(specs, zshQuote("--"+flag.Name+suffix))
+		if flag.Shorthand != "" {
+			specs = append(specs, zshQuote("-"+flag.Shorthand+strings.Replace(suffix, "=[", "[", 1)))
+		}
+	}
+	return specs
+}
+
+// zshEscape escapes the characters special in the descriptions of
+// _arguments specs.
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(s)
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishCompletion offers the root flags and commands until a command is
+// given, and the flags of the command after it.
+func fishCompletion(w io.Writer) {
+	fmt.Fprintf(w, "# fish completion for %s, generated by \"%[1]s completion fish\".\n", program)
+	fmt.Fprintf(w, "complete -c %s -f\n", program)
+	for _, name := range commandNames() {
+		fmt.Fprintf(w, "complete -c %s -n __fish_use_subcommand -a %s -d %s\n", program, name, fishQuote(commands[name].summary))
+	}
+	for _, name := range append([]string{""}, commandNames()...) {
+		condition := "__fish_use_subcommand"
+		if name != "" {
+			condition = "'__fish_seen_subcommand_from " + name + "'"
+		}
+		for _, flag := range visibleFlags(name) {
+			line := fmt.Sprintf("complete -c %s -n %s -l %s", program, condition, flag.Name)
+			if flag.Shorthand != "" {
+				line += " -s " + flag.Shorthand
+			}
+			if takesValue(flag) {
+				line += " -r -F"
+			}
+			fmt.Fprintln(w, line+" -d "+fishQuote(flag.Usage))
+		}
+		if name == "" {
+			continue
+		}
+		if words := commands[name].words; len(words) > 0 {
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s\n", program, condition, fishQuote(strings.Join(words, " ")))
+		} else if commands[name].args != "" {
+			fmt.Fprintf(w, "complete -c %s -n %s -F\n", program, condition)
+		}
+	}
+}
+
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandFlags(t *testing.T) {
+	for _, name := range commandNames() {
+		if commands[name].flags != nil && !commandFlags(name).HasFlags() {
+			t.Errorf("command %s defines no flags", name)
+		}
+	}
+	if commandFlags("doctor").Lookup("target") == nil {
+		t.Error("expected the --target flag of doctor")
+	}
+
+	var out strings.Builder
+	bashCompletion(&out)
+	if !strings.Contains(out.String(), "\tconfig) flags=\"\" words=\"example\" values=\"\" ;;\n") {
+		t.Errorf("unexpected bash completion\n%s", out.String())
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CompressionConfig configures gzip compression of HTTP responses.
+type CompressionConfig struct {
+	// Enabled turns on gzip compression for clients that accept it.
+	Enabled bool `mapstructure:"enabled"`
+	// Level is the gzip compression level from 1 (fastest) to 9 (best).
+	// -1 selects the default level and -2 Huffman-only compression.
+	Level int `mapstructure:"level"`
+}
+
+// validate checks the compression level.
+func (c CompressionConfig) validate() error {
+	if c.Level < gzip.HuffmanOnly || c.Level > gzip.BestCompression {
+		return fmt.Errorf("invalid web.compression.level %d, must be between %d and %d", c.Level, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	return nil
+}
+
+// handler compresses the responses of next with gzip if enabled and
+// accepted by the client. The promhttp handlers leave compression to it so
+// that all endpoints share the same settings.
+func (c CompressionConfig) handler(next http.Handler) http.Handler {
+	if !c.Enabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		// Responses to HEAD requests have no body to compress.
+		if r.Method == http.MethodHead || !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gz := &gzipResponseWriter{ResponseWriter: w, level: c.Level}
+		defer gz.Close()
+		next.ServeHTTP(gz, r)
+	})
+}
+
+// acceptsGzip reports whether the request lists gzip in Accept-Encoding.
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the body written to the response. The gzip
+// writer is created lazily so that responses without a body stay empty.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	level       int
+	writer      *gzip.Writer
+	wroteHeader bool
+	// bodiless is set for statuses that must not have a body, which are
+	// passed on without Content-Encoding.
+	bodiless bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		// Informational responses precede the final one.
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusNoContent || status == http.StatusNotModified {
+			w.bodiless = true
+		} else {
+			w.Header().Del("Content-Length")
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.bodiless {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.writer == nil {
+		// The level has been validated when loading the configuration.
+		w.writer, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	return w.writer.Write(b)
+}
+
+// Close flushes the compressed body.
+func (w *gzipResponseWriter) Close() error {
+	if w.writer == nil {
+		return nil
+	}
+	return w.writer.Close()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompressionBodiless(t *testing.T) {
+	handler := CompressionConfig{Enabled: true, Level: -1}.handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/not-modified" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	for _, tt := range []struct {
+		method, path, encoding string
+	}{
+		{http.MethodGet, "/", "gzip"},
+		{http.MethodHead, "/", ""},
+		{http.MethodGet, "/not-modified", ""},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%s %s: expected Content-Encoding %q, got %q", tt.method, tt.path, tt.encoding, got)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Code generated by gen_configdoc.go; DO NOT EDIT.
+
+package main
+
+// fieldDocs holds the doc comments of the fields of the configuration
+// structs by package, type and field name.
+var fieldDocs = map[string]string{
+	"config.Options.Bounds":                   "Bounds are the plausibility limits of the readings.",
+	"config.Options.ClockOffset":              "ClockOffset reads the device clock and exports its offset against\nthe exporter clock.",
+	"config.Options.DNSCacheTTL":              "DNSCacheTTL is the time the addresses of target host names are\ncached. Host names are resolved on every scrape if 0.",
+	"config.Options.DerivedMetrics":           "DerivedMetrics exports the heat index and the absolute humidity of\ndevices measuring both temperature and relative humidity.",
+	"config.Options.DeviceType":               "DeviceType selects the measurement types of the sensor channels and\nwith them the exported metric families, see the DeviceType* constants.",
+	"config.Options.ErrorTokens":              "ErrorTokens are additional placeholders reported by the firmware\nfor channels without a probe, besides \"--\" and the variants of the\nknown firmware languages like \"n/a\" or \"k.A.\". Other values that are\nno number are counted as parse failures.",
+	"config.Options.ErrorValues":              "ErrorValues selects how absent or unparsable sensor values are\nexported, see the ErrorValues* constants.",
+	"config.Options.IntegerValues":            "IntegerValues reads the integer \"value x 10\" branch of the devices,\navoiding any locale dependent parsing.",
+	"config.Options.LowercaseLabels":          "LowercaseLabels lowercases the room label of the exported metrics.",
+	"config.Options.MaxVarbinds":              "MaxVarbinds is the maximum number of varbinds walked per scrape of a\ntarget. Scrapes exceeding it are truncated and export the readings\nreceived until then as a partial result. Unlimited if 0.",
+	"config.Options.MetricHelp":               "MetricHelp overrides the help texts of metric families by name,\ne.g. to document the sensors of an installation.",
+	"config.Options.MetricNames":              "MetricNames selects between the legacy wut_temperature and the unit\nsuffixed metric names, see the MetricNames* constants.",
+	"config.Options.NormalizeUnit":            "NormalizeUnit reads the unit configured on the device and converts\nall readings to degrees Celsius.",
+	"config.Options.Profiles":                 "Profiles lists optional sets of additional OIDs walked on every\nscrape, see the collector package.",
+	"config.Options.SensorIndexBase":          "SensorIndexBase is the number of the first sensor channel.",
+	"config.Options.SensorLabelName":          "SensorLabelName is the name of the label carrying the sensor of\nthe readings, e.g. \"channel\" or \"probe\" to match other exporters.",
+	"config.Options.SensorLabels":             "SensorLabels selects whether the sensor label is the name configured\non the device or the channel number, see the SensorLabels* constants.",
+	"config.Simulation.Amplitude":             "Amplitude of the sinusoidal variation around the base, 1.5 if\nunset. 0 generates a flat line.",
+	"config.Simulation.Base":                  "Base is the mean temperature in degrees Celsius, 21 if unset.",
+	"config.Simulation.Noise":                 "Noise is the maximum random deviation added to every reading, 0.2\nif unset. 0 generates deterministic readings.",
+	"config.Simulation.Period":                "Period of the sinusoidal variation.",
+	"config.Simulation.Sensors":               "Sensors lists the sensor labels to generate. Defaults to a single\nsensor named \"Sensor 1\".",
+	"config.Target.Addresses":                 "Addresses are tried in order if the device cannot be reached at IP,\ne.g. a secondary management address.",
+	"config.Target.Bounds":                    "Bounds overrides the global plausibility limits for this target.",
+	"config.Target.Device":                    "Device tells apart several devices in the same room. It is part of\nthe target name and suffixed to the sensor labels that occur on more\nthan one device of the room when the room is probed as a whole.",
+	"config.Target.IP":                        "IP is the address or host name of the device.",
+	"config.Target.Priority":                  "Priority is the class of the target when background scrapes queue\nfor a free slot, see the Priority* constants.",
+	"config.Target.PushToken":                 "PushToken authenticates readings pushed by the device. Pushes are\nrejected if no token is configured.",
+	"config.Target.Room":                      "Room is the room label of the exported metrics.",
+	"config.Target.ScrapeInterval":            "ScrapeInterval overrides the global interval of the background\nscrapes for this target.",
+	"config.Target.Simulation":                "Simulation configures the readings served with --simulate.",
+	"config.Target.SmoothingAlpha":            "SmoothingAlpha overrides the global smoothing factor of the moving\naverage of the readings.",
+	"config.Target.Thresholds":                "Thresholds overrides the global temperature limits of the alerting\nrules of this target.",
+	"main.CORSConfig.AllowedHeaders":          "AllowedHeaders are the request headers browsers may send in addition\nto the CORS-safelisted ones, e.g. Authorization.",
+	"main.CORSConfig.AllowedOrigins":          "AllowedOrigins are the origins allowed to query the exporter, \"*\"\nallows any origin. CORS is disabled if it is empty.",
+	"main.CORSConfig.MaxAge":                  "MaxAge is the time browsers may cache the result of a preflight\nrequest.",
+	"main.CompressionConfig.Enabled":          "Enabled turns on gzip compression for clients that accept it.",
+	"main.CompressionConfig.Level":            "Level is the gzip compression level from 1 (fastest) to 9 (best).\n-1 selects the default level and -2 Huffman-only compression.",
+	"main.HAConfig.Identity":                  "Identity identifies this instance in the lease. Defaults to the\nhostname.",
+	"main.HAConfig.LeaseDuration":             "LeaseDuration is the time after which the lease of a leader that\nstopped renewing it can be taken over.",
+	"main.HAConfig.LeaseFile":                 "LeaseFile is the lease shared by all instances, e.g. on a shared\nvolume. HA is disabled if empty.",
+	"main.HardeningConfig.Group":              "Group is the name or GID of the group to switch to. Defaults to the\nprimary group of User.",
+	"main.HardeningConfig.ReadablePaths":      "ReadablePaths and WritablePaths are additional files and\ndirectories accessible with RestrictFilesystem.",
+	"main.HardeningConfig.RestrictFilesystem": "RestrictFilesystem limits file access via Landlock to the files and\ndirectories of the configuration and the additional paths. Requires\nLinux 5.13 or later.",
+	"main.HardeningConfig.User":               "User is the name or UID of the user to switch to, e.g. to bind\nprivileged ports like 162 for traps as root only.",
+	"main.HistoryConfig.Path":                 "Path of the SQLite database. The history is disabled if empty.",
+	"main.HistoryConfig.Retention":            "Retention is the age after which readings are deleted.",
+	"main.ListenerConfig.Address":             "Address is the TCP address to listen on, e.g. \"10.0.0.1:9191\".",
+	"main.ListenerConfig.BasicAuth":           "BasicAuth requires HTTP basic authentication on the listener.",
+	"main.ListenerConfig.Paths":               "Paths restricts the listener to requests for one of the paths or\nthe paths below them. All paths are served if it is empty.",
+	"main.ListenerConfig.TLS":                 "TLS enables HTTPS on the listener.",
+	"main.ListenerTLS.ClientCAFile":           "ClientCAFile, if set, requires clients to present a certificate\nsigned by one of the CAs in the file.",
+	"main.ListenerTLS.ClientCertOptional":     "ClientCertOptional only verifies client certificates if presented,\nleaving it to route policies with client_cert to require them.",
+	"main.OIDCConfig.Audience":                "Audience has to be contained in the aud claim of the tokens.",
+	"main.OIDCConfig.GroupsClaim":             "GroupsClaim is the claim listing the groups of the user.",
+	"main.OIDCConfig.Issuer":                  "Issuer is the URL of the identity provider. OIDC is disabled if it\nis empty.",
+	"main.OIDCConfig.RequiredGroups":          "RequiredGroups lists the groups allowed to access the endpoints.\nMembers of any of them are accepted, all users if it is empty.",
+	"main.RoutePolicy.AllowedNetworks":        "AllowedNetworks lists the networks in CIDR notation requests are\naccepted from. Requests from all networks are accepted if empty.",
+	"main.RoutePolicy.BearerToken":            "BearerToken, BasicAuth and ClientCert are the accepted credentials.\nIf any is set, requests have to authenticate with one of them.",
+	"main.RoutePolicy.ClientCert":             "ClientCert accepts a client certificate verified by the client CAs\nof the TLS listener.",
+	"main.RoutePolicy.ClientNames":            "ClientNames restricts ClientCert to certificates with one of the\nnames as common name or DNS, email, IP or URI subject alternative\nname. Setting it implies ClientCert.",
+	"main.RoutePolicy.Path":                   "Path is the prefix of the paths the policy applies to, including\nthe --web.route-prefix. It matches the path itself and the paths\nbelow it. The policy with the longest matching prefix applies.",
+	"main.TenantConfig.BasicAuth":             "BasicAuth requires HTTP basic authentication on the probes of the\ntenant.",
+	"main.TenantConfig.BearerToken":           "BearerToken requires the token as bearer token on the probes of the\ntenant, accepted as an alternative to BasicAuth if both are set.",
+	"main.TenantConfig.Community":             "Community is the SNMP community of the targets of the tenant,\ndefaults to the global community.",
+	"main.TenantConfig.Labels":                "Labels are added to all metrics of the tenant.",
+	"main.TracingConfig.Endpoint":             "Endpoint is the OTLP/HTTP URL the spans are sent to, e.g.\nhttp://localhost:4318/v1/traces. Tracing is disabled if empty.",
+	"main.TracingConfig.SampleRatio":          "SampleRatio is the fraction of the scrapes traced, from 0 to 1.\nProbes continuing a trace of Prometheus follow its sampling decision.",
+	"main.WebConfig.CORS":                     "CORS configures cross-origin requests from browser applications.",
+	"main.WebConfig.Compression":              "Compression configures gzip compression of the responses.",
+	"main.WebConfig.H2C":                      "H2C enables HTTP/2 without TLS using prior knowledge. It is meant\nfor clients on trusted networks only.",
+	"main.WebConfig.HTTP2":                    "HTTP2 enables HTTP/2 on TLS connections.",
+	"main.WebConfig.IdleTimeout":              "IdleTimeout is the time keep-alive connections are kept open\nwaiting for the next request.",
+	"main.WebConfig.Listeners":                "Listeners are the addresses served, each with its own TLS and\nauthentication settings. Defaults to a single listener on :9191.",
+	"main.WebConfig.ReadHeaderTimeout":        "ReadHeaderTimeout is the time allowed to read the request headers.",
+	"main.WebConfig.ReadTimeout":              "ReadTimeout is the time allowed to read the complete request.",
+	"main.WebConfig.Routes":                   "Routes restrict paths to networks and credentials on all\nlisteners.",
+	"main.WebConfig.WriteTimeout":             "WriteTimeout is the time allowed to handle a request and write the\nresponse. It has to exceed the duration of the slowest scrape.",
+	"main.WebhookConfig.Failures":             "Failures is the number of consecutive failed scrapes after which a\ntarget is reported as down.",
+	"main.WebhookConfig.Timeout":              "Timeout bounds the delivery of a notification.",
+	"main.WebhookConfig.URL":                  "URL receives the notifications. Disabled if empty.",
+	"main.config.AdminToken":                  "AdminToken is the bearer token required by the administrative\nendpoints. They are disabled if neither a token nor OIDC is configured.",
+	"main.config.AggregateWindow":             "AggregateWindow is the window of the minimum, maximum and average\nof the readings exported in daemon mode. Disabled if 0. Changes\nrequire a restart.",
+	"main.config.AlertThresholds":             "AlertThresholds are the default temperature limits of the rules\nemitted by generate-rules.",
+	"main.config.CanaryTarget":                "CanaryTarget is the target probed by /healthz/deep.",
+	"main.config.CanaryTimeout":               "CanaryTimeout bounds the probe of the canary target.",
+	"main.config.Community":                   "Community is the SNMP community of the devices.",
+	"main.config.ConfigVersion":               "ConfigVersion is the schema version of the file, see\nmigrate-config.",
+	"main.config.DrainTimeout":                "DrainTimeout is the time in-flight scrapes are given to finish on\nshutdown before they are cancelled.",
+	"main.config.FIPS":                        "FIPS fails the configuration unless the exporter runs in FIPS 140-3\nmode and only uses FIPS-approved algorithms.",
+	"main.config.HA":                          "HA runs multiple instances as active/standby in daemon mode.\nChanges require a restart.",
+	"main.config.Hardening":                   "Hardening drops privileges after the listeners are bound. Changes\nrequire a restart.",
+	"main.config.History":                     "History records all readings in a local database in daemon mode.\nChanges require a restart.",
+	"main.config.MaxAge":                      "MaxAge is the age after which cached readings are stale. Stale\nreadings are handled according to StaleReadings.",
+	"main.config.MaxConcurrentProbes":         "MaxConcurrentProbes is the number of live probes scraping devices\nat the same time, unlimited if 0. Up to MaxQueuedProbes further\nprobes wait for a free slot, others are rejected with 503. Changes\nrequire a restart.",
+	"main.config.MaxConcurrentScrapes":        "MaxConcurrentScrapes is the number of workers running background\nscrapes, one per target if 0. Queued scrapes start by target\npriority.",
+	"main.config.OIDC":                        "OIDC accepts tokens of an OpenID Connect provider on the\nadministrative endpoints in addition to AdminToken.",
+	"main.config.ProbeTimeout":                "ProbeTimeout is the deadline of a probe. A shorter scrape timeout\nsent by Prometheus takes precedence.",
+	"main.config.RecentReadings":              "RecentReadings is the number of readings of every sensor kept in\nmemory and served on /history in daemon mode. Changes require a\nrestart.",
+	"main.config.RoomAggregates":              "RoomAggregates exports the average, minimum and maximum of the\nlatest readings of every room on /metrics in daemon mode. It\nrequires NormalizeUnit. Changes require a restart.",
+	"main.config.SNMPDebug":                   "SNMPDebug lists the targets whose SNMP packets are traced, \"all\"\ntraces every target. It is set by the --snmp-debug flag.",
+	"main.config.ScrapeInterval":              "ScrapeInterval is the interval of the background scrapes in daemon\nmode.",
+	"main.config.ScrapeJitter":                "ScrapeJitter spreads the background scrapes of the targets over this\nfraction of their interval instead of starting all at once.",
+	"main.config.SelfTest":                    "SelfTest probes all targets once on startup and logs the results.",
+	"main.config.Shard":                       "Shard restricts the targets to those of one of several instances.\nIt is set by the --shard flag.",
+	"main.config.Simulate":                    "Simulate replaces all SNMP traffic with synthetic readings. It is\nset by the --simulate flag and not read from the config file.",
+	"main.config.SmoothingAlpha":              "SmoothingAlpha is the smoothing factor of the moving average of the\nreadings exported in daemon mode, between 0 (disabled) and 1.",
+	"main.config.StaleReadings":               "StaleReadings selects whether stale readings are withheld or served\nand marked, see the staleReadings* constants.",
+	"main.config.StateFile":                   "StateFile is the file the last readings are saved to on shutdown and\nrestored from on startup in daemon mode. Disabled if empty.",
+	"main.config.SyslogListenAddress":         "SyslogListenAddress is the UDP address syslog messages are received\non. The syslog receiver is disabled if it is empty.",
+	"main.config.Targets":                     "Targets are the devices probed by default.",
+	"main.config.Tenants":                     "Tenants are groups of targets probed on their own paths.",
+	"main.config.Tracing":                     "Tracing exports the scrapes as OpenTelemetry spans. Changes require\na restart.",
+	"main.config.TrapListenAddress":           "TrapListenAddress is the UDP address SNMP traps are received on.\nThe trap receiver is disabled if it is empty.",
+	"main.config.Web":                         "Web configures the HTTP server. Changes require a restart.",
+	"main.config.Webhook":                     "Webhook notifies about targets failing background scrapes.",
+}
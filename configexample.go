@@ -0,0 +1,231 @@
+package main
+
+//go:generate go run gen_configdoc.go
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// exampleHeader introduces the output of "config example".
+const exampleHeader = `# Example configuration of wut-temperature-exporter with every option set to
+# its default, generated by "wut-temperature-exporter config example".
+# Optional sections and the lists of targets are commented out.
+`
+
+// constantsReference matches the references of the doc comments to Go
+// constants, which mean nothing to readers of the example.
+var constantsReference = regexp.MustCompile(`,? see the \w+\* constants`)
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// runConfig implements "config example", printing a configuration that
+// documents every option. It is generated from the configuration structs and
+// their doc comments, so it covers all options the exporter reads.
+func runConfig(args []string) int {
+	flags := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: wut-temperature-exporter config example")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || flags.Arg(0) != "example" {
+		flags.Usage()
+		return 2
+	}
+	setupConfig()
+	os.Stdout.Write(exampleConfig())
+	return 0
+}
+
+// exampleConfig renders the configuration struct with the defaults of
+// viper.
+func exampleConfig() []byte {
+	var out bytes.Buffer
+	out.WriteString(exampleHeader)
+	for _, line := range exampleFields(reflect.TypeFor[config](), "", false) {
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// exampleFields returns the lines of the fields of the struct below the
+// dotted path. Optional sections are commented out unless they are part of
+// a section that already is.
+func exampleFields(t reflect.Type, prefix string, commented bool) []string {
+	var lines []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if tag == ",squash" {
+			lines = append(lines, exampleFields(field.Type, prefix, commented)...)
+			continue
+		}
+		key := exampleKey(field)
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+		if doc := exampleDoc(t, field); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				lines = append(lines, strings.TrimRight("# "+line, " "))
+			}
+		}
+
+		ft := field.Type
+		var section []string
+		optional := false
+		switch {
+		case ft.Kind() == reflect.Struct && ft != durationType && optionalFields(ft):
+			section = append([]string{key + ":"}, indent(exampleFields(ft, full, true))...)
+			optional = true
+		case ft.Kind() == reflect.Struct && ft != durationType:
+			section = append([]string{key + ":"}, indent(exampleFields(ft, full, commented))...)
+		case ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct:
+			section = append([]string{key + ":"}, indent(exampleFields(ft.Elem(), full, true))...)
+			optional = true
+		case ft.Kind() == reflect.Pointer:
+			section = []string{key + ": " + exampleValue(ft.Elem(), full)}
+			optional = true
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			section = append([]string{key + ":"}, listItem(exampleFields(ft.Elem(), full, true))...)
+			optional = true
+		default:
+			section = []string{key + ": " + exampleValue(ft, full)}
+		}
+		if optional && !commented {
+			section = comment(section)
+		}
+		lines = append(lines, section...)
+	}
+	return lines
+}
+
+// optionalFields reports whether all fields of the struct are optional,
+// like the limits of Bounds.
+func optionalFields(t reflect.Type) bool {
+	for i := range t.NumField() {
+		if t.Field(i).Type.Kind() != reflect.Pointer {
+			return false
+		}
+	}
+	return true
+}
+
+// exampleKey returns the key of the field in the configuration file. Like
+// mapstructure, fields without a tag use their name.
+func exampleKey(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ","); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+// exampleDoc returns the doc comment of the field with the names of the
+// fields of the struct replaced by their keys. Names in capitals are only
+// replaced at the start, elsewhere they usually are acronyms.
+func exampleDoc(t reflect.Type, field reflect.StructField) string {
+	doc := fieldDocs[path.Base(t.PkgPath())+"."+t.Name()+"."+field.Name]
+	if doc == "" {
+		return ""
+	}
+	doc = constantsReference.ReplaceAllString(doc, "")
+	var names []string
+	keys := map[string]string{}
+	for i := range t.NumField() {
+		if f := t.Field(i); f.IsExported() && !f.Anonymous {
+			names = append(names, regexp.QuoteMeta(f.Name))
+			keys[f.Name] = exampleKey(f)
+		}
+	}
+	if rest, ok := strings.CutPrefix(doc, field.Name); ok {
+		doc = keys[field.Name] + rest
+	}
+	return regexp.MustCompile(`\b(`+strings.Join(names, "|")+`)\b`).ReplaceAllStringFunc(doc, func(name string) string {
+		if strings.ToUpper(name) == name {
+			return name
+		}
+		return keys[name]
+	})
+}
+
+// exampleValue returns the default of the key as YAML.
+func exampleValue(t reflect.Type, key string) string {
+	if t == durationType {
+		return exampleDuration(viper.GetDuration(key))
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return strconv.Quote(viper.GetString(key))
+	case reflect.Bool:
+		return strconv.FormatBool(viper.GetBool(key))
+	case reflect.Int, reflect.Int64, reflect.Uint16:
+		return strconv.Itoa(viper.GetInt(key))
+	case reflect.Float64:
+		return strconv.FormatFloat(viper.GetFloat64(key), 'g', -1, 64)
+	case reflect.Slice:
+		values := viper.GetStringSlice(key)
+		for i, value := range values {
+			values[i] = strconv.Quote(value)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case reflect.Map:
+		return "{}"
+	}
+	panic(fmt.Sprintf("unsupported type %s of %s", t, key))
+}
+
+// exampleDuration formats the duration like time.Duration.String without
+// zero minutes and seconds, e.g. 168h instead of 168h0m0s.
+func exampleDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func indent(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return lines
+}
+
+// listItem renders the lines of a struct as the only item of a list.
+func listItem(lines []string) []string {
+	first := true
+	for i, line := range lines {
+		if first && !strings.HasPrefix(line, "#") {
+			lines[i], first = "- "+line, false
+			continue
+		}
+		lines[i] = "  " + line
+	}
+	return lines
+}
+
+func comment(lines []string) []string {
+	for i, line := range lines {
+		lines[i] = "# " + line
+	}
+	return lines
+}
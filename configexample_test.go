@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExampleConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setupConfig()
+	example := exampleConfig()
+
+	if err := viper.ReadConfig(bytes.NewReader(example)); err != nil {
+		t.Fatal(err)
+	}
+	var c config
+	if err := viper.Unmarshal(&c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.validate(); err != nil {
+		t.Errorf("example does not validate: %v", err)
+	}
+
+	// All keys of the commented out sections have to be known as well.
+	uncommented := regexp.MustCompile(`(?m)^( *)# ( *(- )?[a-z0-9_]+:( |$))`).ReplaceAll(example, []byte("$1$2"))
+	unknown, err := unknownConfigKeys(uncommented)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) > 0 {
+		t.Errorf("example has unknown keys %q", unknown)
+	}
+}
+
+func TestFieldDocsUpToDate(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	output := filepath.Join(t.TempDir(), "configdoc_gen.go")
+	if out, err := exec.Command("go", "run", "gen_configdoc.go", "-o", output).CombinedOutput(); err != nil {
+		t.Fatalf("error running gen_configdoc.go: %v\n%s", err, out)
+	}
+	generated, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile("configdoc_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, current) {
+		t.Error("configdoc_gen.go is stale, run go generate")
+	}
+}
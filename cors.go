@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures cross-origin requests from browser applications.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to query the exporter, "*"
+	// allows any origin. CORS is disabled if it is empty.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	// AllowedHeaders are the request headers browsers may send in addition
+	// to the CORS-safelisted ones, e.g. Authorization.
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	// MaxAge is the time browsers may cache the result of a preflight
+	// request.
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+// allowed returns the value of Access-Control-Allow-Origin for the origin,
+// or an empty string if it is not allowed.
+func (c CORSConfig) allowed(origin string) string {
+	if slices.Contains(c.AllowedOrigins, "*") {
+		return "*"
+	}
+	if slices.Contains(c.AllowedOrigins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// handler adds the CORS headers to responses for allowed origins and
+// answers preflight requests.
+func (c CORSConfig) handler(next http.Handler) http.Handler {
+	if len(c.AllowedOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := c.allowed(origin)
+		if allowed == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT")
+		if len(c.AllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+		}
+		if c.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// poller scrapes all configured targets in the background, each at its own
+// interval, and keeps the latest result of every target.
+type poller struct {
+	logger *zap.Logger
+	reload chan struct{}
+	// scrapeCtx cancels in-flight scrapes. It is independent of the
+	// context passed to Run, so that stopping the poller lets running
+	// scrapes finish.
+	scrapeCtx context.Context
+	// started separates restored readings from those scraped since.
+	started time.Time
+	// recorders receive all readings.
+	recorders []recorder
+	// notifier, if set, observes the results of all scrapes.
+	notifier *webhookNotifier
+	// aggregators receive all temperature readings and export metrics
+	// derived from them alongside the cached results.
+	aggregators []aggregator
+
+	mu      sync.RWMutex
+	config  config
+	results map[string]collector.Result
+	// polling is set while Run is polling the targets, which a standby
+	// in HA mode does not.
+	polling bool
+}
+
+func newPoller(scrapeCtx context.Context, config config, logger *zap.Logger) *poller {
+	return &poller{
+		scrapeCtx: scrapeCtx,
+		started:   time.Now(),
+		config:    config,
+		logger:    logger,
+		reload:    make(chan struct{}, 1),
+		results:   make(map[string]collector.Result),
+	}
+}
+
+// Reload replaces the configuration and restarts polling of all targets.
+func (p *poller) Reload(config config) {
+	p.mu.Lock()
+	p.config = config
+	p.mu.Unlock()
+
+	select {
+	case p.reload <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts polling all targets and blocks until the context is cancelled
+// and all running scrapes have finished. Due scrapes are queued by the
+// scheduler and run by a pool of max_concurrent_scrapes workers.
+func (p *poller) Run(ctx context.Context) {
+	p.setPolling(true)
+	defer p.setPolling(false)
+	for {
+		p.mu.Lock()
+		config := p.config
+		// Drop results of targets that are no longer configured.
+		for name := range p.results {
+			if _, ok := config.findTarget(name); !ok {
+				delete(p.results, name)
+			}
+		}
+		p.mu.Unlock()
+
+		runCtx, cancel := context.WithCancel(ctx)
+		queue := newScrapeQueue()
+		var wg sync.WaitGroup
+		workers := config.workers()
+		scrapeWorkers.Set(float64(workers))
+		for id := range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.work(runCtx, config, queue, id)
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.schedule(runCtx, config, queue)
+		}()
+
+		select {
+		case <-ctx.Done():
+		case <-p.reload:
+		}
+		cancel()
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (p *poller) setPolling(polling bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.polling = polling
+}
+
+// jitterOffset returns the delay of the first scrape of the target within
+// the jitter fraction of the interval. It is derived from the target name,
+// so that every target keeps its slot across restarts and reloads.
+func jitterOffset(target wutconfig.Target, interval time.Duration, jitter float64) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(target.Name()))
+	return time.Duration(float64(h.Sum64()%1e6) / 1e6 * jitter * float64(interval))
+}
+
+func (p *poller) scrape(config config, target wutconfig.Target) {
+	result := config.collector(target, p.logger).Scrape(p.scrapeCtx)
+	if result.Err != nil {
+		p.logger.Error("Error scraping SNMP target", zap.String("ip", target.IP), zap.String("reason", collector.Reason(result.Err)), zap.Error(result.Err))
+	}
+
+	p.record(target, result.Readings)
+	if p.notifier != nil {
+		p.notifier.Observe(target, result)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results[target.Name()] = result
+}
+
+// record passes the readings of the target to all recorders, and its
+// temperatures to all aggregators.
+func (p *poller) record(target wutconfig.Target, readings []collector.Reading) {
+	if len(readings) == 0 {
+		return
+	}
+	for _, r := range p.recorders {
+		r.Record(target.Name(), readings)
+	}
+	var temperatures []collector.Reading
+	for _, reading := range readings {
+		if collector.IsTemperature(reading.Unit) {
+			temperatures = append(temperatures, reading)
+		}
+	}
+	if len(temperatures) == 0 {
+		return
+	}
+	for _, a := range p.aggregators {
+		a.Record(target.Name(), temperatures)
+	}
+}
+
+// Aggregates returns the metrics of all aggregators for the target.
+func (p *poller) Aggregates(c collector.Collector, target wutconfig.Target, now time.Time) []prometheus.Metric {
+	var metrics []prometheus.Metric
+	for _, a := range p.aggregators {
+		metrics = append(metrics, a.Metrics(c, target.Name(), now)...)
+	}
+	return metrics
+}
+
+// scrapeBudget is the upper bound of a single scrape including all SNMP
+// retries. It is used to tell slow targets apart from hung scrapes.
+const scrapeBudget = time.Minute
+
+// Healthy returns an error if any target has not completed a scrape for
+// longer than twice its interval, which indicates a hung poller. Results
+// are expected to age while the poller is not running, e.g. on a standby
+// or after restoring them from the state file.
+func (p *poller) Healthy() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.polling {
+		return nil
+	}
+	for _, target := range p.config.Targets {
+		result, ok := p.results[target.Name()]
+		if !ok {
+			// The first scrape is still running.
+			continue
+		}
+		if age := time.Since(result.Timestamp); age > 2*target.Interval(p.config.ScrapeInterval)+scrapeBudget {
+			return fmt.Errorf("target %s has not been scraped for %s", target.Name(), age.Round(time.Second))
+		}
+	}
+	return nil
+}
+
+// Push merges readings pushed by the device into the cached result of the
+// target, replacing older readings of the same sensors.
+func (p *poller) Push(target wutconfig.Target, readings []collector.Reading) {
+	p.record(target, readings)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.results[target.Name()]
+	if !ok {
+		result = collector.Result{Timestamp: time.Now()}
+	}
+	merged := make([]collector.Reading, 0, len(result.Readings)+len(readings))
+	pushed := make(map[string]bool, len(readings))
+	for _, reading := range readings {
+		pushed[reading.Sensor] = true
+	}
+	for _, reading := range result.Readings {
+		if !pushed[reading.Sensor] {
+			merged = append(merged, reading)
+		}
+	}
+	result.Readings = append(merged, readings...)
+	p.results[target.Name()] = result
+}
+
+// Result returns the latest scrape result of the target.
+func (p *poller) Result(target wutconfig.Target) (collector.Result, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	result, ok := p.results[target.Name()]
+	return result, ok
+}
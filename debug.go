@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// walkResponse is the JSON body served by walkHandler.
+type walkResponse struct {
+	Target   string              `json:"target"`
+	OID      string              `json:"oid"`
+	Varbinds []collector.Varbind `json:"varbinds"`
+	// Error is set if the walk failed, Varbinds then holds the varbinds
+	// received before.
+	Error string `json:"error,omitempty"`
+}
+
+// walkHandler serves the raw varbinds of the target passed in the target
+// parameter below the OID passed in the oid parameter, which defaults to
+// the W&T enterprise subtree.
+func walkHandler(store *configStore, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r.Context(), fallback)
+		query := r.URL.Query()
+
+		target := query.Get("target")
+		if len(query["target"]) != 1 || target == "" {
+			writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "'target' parameter must be specified once", Hint: "pass the room or IP of a configured target"})
+			return
+		}
+		oid := query.Get("oid")
+		if oid == "" {
+			oid = collector.WalkRoot
+		}
+
+		config := store.Get()
+		t, ok := config.findTarget(target)
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Target not found", Target: target, Hint: "pass the room or IP of a configured target"})
+			return
+		}
+
+		varbinds, err := config.collector(t, logger).Walk(r.Context(), oid)
+		for i := range varbinds {
+			if value := redaction.Redact(varbinds[i].Value); value != varbinds[i].Value {
+				varbinds[i].Value, varbinds[i].Hex = value, ""
+			}
+		}
+		response := walkResponse{Target: t.Name(), OID: oid, Varbinds: varbinds}
+		status := http.StatusOK
+		if err != nil {
+			logger.Error("Error walking SNMP target", zap.String("ip", t.IP), zap.String("oid", oid), zap.Error(err))
+			response.Error = redaction.Redact(err.Error())
+			status = http.StatusBadGateway
+		}
+		if deadlineExceeded(r.Context()) {
+			status = http.StatusGatewayTimeout
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
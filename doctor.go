@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+)
+
+// maxClockOffset is the offset of the device clock above which doctor
+// warns, as the timestamps of pushed readings and traps become unreliable.
+const maxClockOffset = time.Minute
+
+// Results of the checks of doctor.
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+	checkSkip = "SKIP"
+)
+
+// doctorReport prints the results of the checks as they complete.
+type doctorReport struct {
+	w      io.Writer
+	failed bool
+}
+
+// add prints the result of a check and the hint on how to fix it.
+func (r *doctorReport) add(status, check, detail, hint string) {
+	if status == checkFail {
+		r.failed = true
+	}
+	fmt.Fprintf(r.w, "[%-4s] %-8s %s\n", status, check, detail)
+	if hint != "" && status != checkOK {
+		fmt.Fprintf(r.w, "       %s\n", hint)
+	}
+}
+
+// doctorFlags are the flags of the doctor command.
+type doctorFlags struct {
+	targetName *string
+	timeout    *time.Duration
+}
+
+// newDoctorFlags defines the flags of the doctor command on the flag set.
+func newDoctorFlags(flags *pflag.FlagSet) doctorFlags {
+	f := doctorFlags{
+		targetName: flags.String("target", "", "Room or IP of a configured target, or the address of any device"),
+		timeout:    flags.Duration("timeout", 15*time.Second, "Timeout of every step"),
+	}
+	addPublicKeyFlag(flags)
+	return f
+}
+
+// runDoctor checks a target step by step, from resolving its name to
+// parsing its readings, and prints what to fix for every failed step.
+func runDoctor(args []string) int {
+	flags := pflag.NewFlagSet("doctor", pflag.ContinueOnError)
+	f := newDoctorFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *f.targetName == "" {
+		fmt.Fprintln(os.Stderr, "--target is required")
+		return 2
+	}
+
+	setupConfig()
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "No valid configuration found: %v\n", err)
+		return 1
+	}
+	report := &doctorReport{w: os.Stdout}
+	target, ok := config.findTarget(*f.targetName)
+	if ok {
+		report.add(checkOK, "config", fmt.Sprintf("target %s at %s", target.Name(), target.IP), "")
+	} else {
+		target = wutconfig.Target{IP: *f.targetName}
+		report.add(checkWarn, "config", fmt.Sprintf("%s is not configured, checking it with the global options", *f.targetName), "Add it to the targets of the configuration file to export its readings.")
+	}
+	diagnose(context.Background(), config.collector(target, zap.NewNop()), *f.timeout, report)
+	if report.failed {
+		return 1
+	}
+	return 0
+}
+
+// diagnose runs the checks of the collector. Checks depending on a failed
+// one are skipped.
+func diagnose(ctx context.Context, c collector.Collector, timeout time.Duration, report *doctorReport) {
+	host := c.Ip
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	step, cancel := context.WithTimeout(ctx, timeout)
+	ip, err := resolveHost(step, host)
+	cancel()
+	switch {
+	case err != nil:
+		report.add(checkFail, "dns", err.Error(), "Check the host name, the DNS servers of the exporter or configure the IP address instead.")
+	case ip.String() == host:
+		report.add(checkOK, "dns", host+" is an IP address", "")
+	default:
+		report.add(checkOK, "dns", fmt.Sprintf("%s resolves to %s", host, ip), "")
+	}
+
+	if ip == nil {
+		report.add(checkSkip, "icmp", "the address is unknown", "")
+	} else if replies, rtt, err := ping(ip, 3, time.Second); errors.Is(err, os.ErrPermission) {
+		report.add(checkSkip, "icmp", "sending ICMP requires root or CAP_NET_RAW", "")
+	} else if err != nil {
+		report.add(checkFail, "icmp", err.Error(), "Check the routes of the exporter to the device.")
+	} else if replies == 0 {
+		report.add(checkWarn, "icmp", "no echo replies", "The device is offline or ICMP is filtered. Check its power and cabling and the firewalls on the way.")
+	} else {
+		report.add(checkOK, "icmp", fmt.Sprintf("%d/3 echo replies, %s average", replies, rtt.Round(10*time.Microsecond)), "")
+	}
+
+	step, cancel = context.WithTimeout(ctx, timeout)
+	identity, err := c.Identify(step)
+	cancel()
+	snmpOK := err == nil
+	switch {
+	case errors.Is(err, collector.ErrTimeout):
+		report.add(checkFail, "snmp", err.Error(), "Check that SNMP is enabled on the device, the community matches and UDP port 161 is not filtered.")
+	case err != nil:
+		report.add(checkFail, "snmp", err.Error(), "Check the address of the target and that its SNMP agent is enabled.")
+	case !identity.IsWUT():
+		report.add(checkWarn, "snmp", fmt.Sprintf("%q (%s) is not a W&T device", identity.Description, identity.ObjectID), "Check the address of the target.")
+	default:
+		report.add(checkOK, "snmp", fmt.Sprintf("%s (%s)", identity.Description, identity.ObjectID), "")
+	}
+
+	var values, labels []collector.Varbind
+	if !snmpOK {
+		report.add(checkSkip, "oids", "requires SNMP", "")
+	} else {
+		step, cancel = context.WithTimeout(ctx, timeout)
+		values, err = c.Walk(step, c.ValueOID())
+		if err == nil {
+			labels, err = c.Walk(step, collector.LabelOID)
+		}
+		cancel()
+		switch {
+		case err != nil:
+			report.add(checkFail, "oids", err.Error(), "The device stopped responding mid-walk, check for packet loss.")
+		case len(values) == 0:
+			report.add(checkFail, "oids", "the device exposes no sensor values at "+c.ValueOID(), "The device is not a Web-Thermometer or its firmware does not support SNMP for the sensors. Toggle integer_values if only the other branch is available.")
+		default:
+			report.add(checkOK, "oids", fmt.Sprintf("%d sensor values and %d names", len(values), len(labels)), "")
+		}
+	}
+
+	if len(values) == 0 {
+		report.add(checkSkip, "values", "requires the sensor values", "")
+	} else {
+		diagnoseValues(c, values, labels, report)
+	}
+
+	step, cancel = context.WithTimeout(ctx, timeout)
+	offset, err := c.DeviceClockOffset(step)
+	cancel()
+	switch {
+	case err != nil:
+		report.add(checkSkip, "clock", "the device clock is not readable via SNMP or HTTP", "")
+	case offset.Abs() > maxClockOffset:
+		report.add(checkWarn, "clock", fmt.Sprintf("the device clock is off by %s", offset.Round(time.Second)), "Configure an NTP server on the device.")
+	default:
+		report.add(checkOK, "clock", fmt.Sprintf("the device clock is off by %s", offset.Round(time.Millisecond)), "")
+	}
+}
+
+// diagnoseValues parses the sensor values like a scrape and reports every
+// channel that yields no reading.
+func diagnoseValues(c collector.Collector, values, labels []collector.Varbind, report *doctorReport) {
+	names := make(map[int]string, len(labels))
+	for _, label := range labels {
+		names[collector.OIDIndex(label.OID)] = label.Value
+	}
+	var readings, disconnected []string
+	failed := false
+	for _, value := range values {
+		sensor := names[collector.OIDIndex(value.OID)]
+		if sensor == "" {
+			sensor = fmt.Sprint(collector.OIDIndex(value.OID) - 1 + c.SensorIndexBase)
+		}
+		raw := value.Value
+		if tenths, err := strconv.Atoi(raw); err == nil && c.IntegerValues {
+			// The integer branch reports tenths of a degree.
+			raw = strconv.FormatFloat(float64(tenths)/10, 'f', 1, 64)
+		}
+		if collector.IsPlaceholder(value.Value, c.ErrorTokens) {
+			disconnected = append(disconnected, sensor)
+			continue
+		}
+		reading, ok := c.ParseReading(sensor, raw, collector.UnitCelsius, time.Now())
+		if !ok {
+			failed = true
+			report.add(checkFail, "values", fmt.Sprintf("sensor %s reports %q, which is unparsable or outside of the bounds", sensor, value.Value), "Check the bounds of the target, or set error_tokens if the value is a placeholder of the firmware language or integer_values to avoid locale dependent parsing.")
+			continue
+		}
+		readings = append(readings, fmt.Sprintf("%s %g", sensor, reading.Value))
+	}
+	if len(disconnected) > 0 {
+		report.add(checkWarn, "values", "no probe connected to "+strings.Join(disconnected, ", "), "Check the cabling of the probes, or the channels are unused.")
+	}
+	if !failed && len(readings) > 0 {
+		report.add(checkOK, "values", strings.Join(readings, ", "), "")
+	}
+}
+
+// resolveHost returns the address of the host, which may already be an IP
+// address.
+func resolveHost(ctx context.Context, host string) (net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, nil
+	}
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	return addresses[0].IP, nil
+}
+
+// ping sends ICMP echo requests to the address and returns the number of
+// replies and their average round trip time. Raw ICMP sockets require root
+// or CAP_NET_RAW, os.ErrPermission is returned otherwise.
+func ping(ip net.IP, count int, timeout time.Duration) (int, time.Duration, error) {
+	network, address, request, reply := "ip4:icmp", "0.0.0.0", byte(8), byte(0)
+	if ip.To4() == nil {
+		network, address, request, reply = "ip6:ipv6-icmp", "::", 128, 129
+	}
+	conn, err := net.ListenPacket(network, address)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+	replies := 0
+	var total time.Duration
+	buf := make([]byte, 1500)
+	for seq := range count {
+		message := []byte{request, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq), 'w', 'u', 't'}
+		if request == 8 {
+			// The kernel computes the checksum of ICMPv6 messages.
+			sum := icmpChecksum(message)
+			message[2], message[3] = byte(sum>>8), byte(sum)
+		}
+		start := time.Now()
+		if _, err := conn.WriteTo(message, &net.IPAddr{IP: ip}); err != nil {
+			return replies, 0, err
+		}
+		conn.SetReadDeadline(start.Add(timeout))
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				break
+			}
+			if from, ok := from.(*net.IPAddr); ok && from.IP.Equal(ip) && n >= 8 && buf[0] == reply &&
+				int(buf[4])<<8|int(buf[5]) == id && int(buf[6])<<8|int(buf[7]) == seq {
+				replies++
+				total += time.Since(start)
+				break
+			}
+		}
+	}
+	if replies == 0 {
+		return 0, 0, nil
+	}
+	return replies, total / time.Duration(replies), nil
+}
+
+// icmpChecksum is the internet checksum of RFC 1071.
+func icmpChecksum(message []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(message); i += 2 {
+		sum += uint32(message[i])<<8 | uint32(message[i+1])
+	}
+	if len(message)%2 == 1 {
+		sum += uint32(message[len(message)-1]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestDiagnose(t *testing.T) {
+	config := startAgent(t, "public")
+	var out strings.Builder
+	report := &doctorReport{w: &out}
+	diagnose(t.Context(), config.collector(config.Targets[0], zap.NewNop()), 5*time.Second, report)
+
+	if report.failed {
+		t.Errorf("expected all checks to pass, got\n%s", out.String())
+	}
+	for _, line := range []string{
+		"[OK  ] dns      127.0.0.1 is an IP address\n",
+		"[OK  ] snmp     Web-Thermometer NTC 57613 (.1.3.6.1.4.1.5040.1.2.6)\n",
+		"[WARN] values   no probe connected to 3\n",
+		"[OK  ] values   Rack 1 21.4, Rack 2 23.1\n",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("expected %q in\n%s", line, out.String())
+		}
+	}
+}
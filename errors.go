@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// Machine readable codes of API errors.
+const (
+	errorCodeBadRequest       = "bad_request"
+	errorCodeUnknownTarget    = "unknown_target"
+	errorCodeUnknownTenant    = "unknown_tenant"
+	errorCodeNotScraped       = "not_scraped"
+	errorCodeSNMPTimeout      = "snmp_timeout"
+	errorCodeSNMPConnect      = "snmp_connect"
+	errorCodeSNMPAuth         = "snmp_auth"
+	errorCodeSNMPParse        = "snmp_parse"
+	errorCodeUnauthorized     = "unauthorized"
+	errorCodeForbidden        = "forbidden"
+	errorCodeMethodNotAllowed = "method_not_allowed"
+	errorCodeNotConfigured    = "not_configured"
+	errorCodeReloadFailed     = "reload_failed"
+	errorCodeInternal         = "internal"
+	errorCodeOverloaded       = "overloaded"
+)
+
+// apiError is the JSON body of error responses.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Target  string `json:"target,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// writeError responds with the error encoded as JSON. Secrets in the
+// message, e.g. of wrapped SNMP errors, are redacted.
+func writeError(w http.ResponseWriter, status int, err apiError) {
+	err.Message = redaction.Redact(err.Message)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(err)
+}
+
+// writeScrapeError responds with the status matching the class of the
+// scrape error of the target.
+func writeScrapeError(w http.ResponseWriter, target string, err error) {
+	switch {
+	case errors.Is(err, collector.ErrTimeout):
+		writeError(w, http.StatusGatewayTimeout, apiError{Code: errorCodeSNMPTimeout, Message: err.Error(), Target: target, Hint: "check that the device is reachable via SNMP"})
+	case errors.Is(err, collector.ErrAuth):
+		writeError(w, http.StatusForbidden, apiError{Code: errorCodeSNMPAuth, Message: err.Error(), Target: target, Hint: "check the SNMP credentials of the target"})
+	case errors.Is(err, collector.ErrParse):
+		writeError(w, http.StatusBadGateway, apiError{Code: errorCodeSNMPParse, Message: err.Error(), Target: target, Hint: "inspect the raw values via /debug/walk"})
+	default:
+		writeError(w, http.StatusServiceUnavailable, apiError{Code: errorCodeSNMPConnect, Message: err.Error(), Target: target, Hint: "check that the device is reachable via SNMP"})
+	}
+}
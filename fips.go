@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/fips140"
+	"crypto/rsa"
+	"crypto/tls"
+	"errors"
+	"fmt"
+)
+
+// fipsEnabled reports whether the binary runs in FIPS 140-3 mode, which
+// checkFIPS requires if fips is set. Tests replace it.
+var fipsEnabled = fips140.Enabled
+
+// minFIPSRSABits is the smallest FIPS-approved RSA key size.
+const minFIPSRSABits = 2048
+
+// errFIPSDisabled is returned if fips is set but the binary does not run
+// with the Go Cryptographic Module in FIPS 140-3 mode.
+var errFIPSDisabled = errors.New("fips requires FIPS 140-3 mode, build with GOFIPS140=v1.0.0 or run with GODEBUG=fips140=on")
+
+// checkFIPS fails if fips is set and the configuration is not restricted to
+// FIPS-approved algorithms. In FIPS 140-3 mode crypto/tls itself restricts
+// the listeners and outgoing connections to approved versions, cipher
+// suites and curves. SNMP uses no cryptography as only SNMPv1 is
+// supported. SOPS encryption and prehashed minisign signatures are
+// rejected while reading the file, and OIDC signing keys below 2048 bits
+// are skipped in FIPS 140-3 mode.
+func (c config) checkFIPS() error {
+	if !c.FIPS {
+		return nil
+	}
+	if !fipsEnabled() {
+		return errFIPSDisabled
+	}
+	for _, listener := range c.Web.Listeners {
+		if listener.TLS == nil {
+			continue
+		}
+		cert, err := tls.LoadX509KeyPair(listener.TLS.CertFile, listener.TLS.KeyFile)
+		if err != nil {
+			return fmt.Errorf("error loading certificate of listener %s: %w", listener.Address, err)
+		}
+		if err := fipsApprovedKey(cert.PrivateKey); err != nil {
+			return fmt.Errorf("certificate of listener %s: %w", listener.Address, err)
+		}
+	}
+	return nil
+}
+
+// fipsApprovedKey checks that TLS handshakes can be signed with the key in
+// FIPS 140-3 mode.
+func fipsApprovedKey(key any) error {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minFIPSRSABits {
+			return fmt.Errorf("RSA key of %d bits is not FIPS-approved, must have at least %d", key.N.BitLen(), minFIPSRSABits)
+		}
+		return nil
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+		return fmt.Errorf("ECDSA curve %s is not FIPS-approved", key.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return nil
+	}
+	return fmt.Errorf("key type %T is not FIPS-approved", key)
+}
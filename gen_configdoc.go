@@ -0,0 +1,99 @@
+//go:build ignore
+
+// gen_configdoc extracts the doc comments of the fields of the
+// configuration structs into configdoc_gen.go, used by "config example".
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	output := flag.String("o", "configdoc_gen.go", "File to write")
+	flag.Parse()
+
+	docs := map[string]string{}
+	for _, dir := range []string{".", "pkg/config"} {
+		packages, err := parser.ParseDir(token.NewFileSet(), dir, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, parser.ParseComments)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for name, pkg := range packages {
+			for _, file := range pkg.Files {
+				collect(name, file, docs)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var out bytes.Buffer
+	out.WriteString("// Code generated by gen_configdoc.go; DO NOT EDIT.\n\npackage main\n\n")
+	out.WriteString("// fieldDocs holds the doc comments of the fields of the configuration\n// structs by package, type and field name.\nvar fieldDocs = map[string]string{\n")
+	for _, key := range keys {
+		fmt.Fprintf(&out, "\t%q: %s,\n", key, strconv.Quote(docs[key]))
+	}
+	out.WriteString("}\n")
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// collect adds the doc comments of the fields of all structs in the file
+// with mapstructure tags.
+func collect(pkg string, file *ast.File, docs map[string]string) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+		fields, ok := spec.Type.(*ast.StructType)
+		if !ok || !tagged(fields) {
+			return true
+		}
+		for _, field := range fields.Fields.List {
+			if field.Doc == nil {
+				continue
+			}
+			for _, name := range field.Names {
+				docs[pkg+"."+spec.Name.Name+"."+name.Name] = strings.TrimSpace(field.Doc.Text())
+			}
+		}
+		return true
+	})
+}
+
+// tagged reports whether any field of the struct has a mapstructure tag.
+func tagged(fields *ast.StructType) bool {
+	for _, field := range fields.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag, _ := strconv.Unquote(field.Tag.Value)
+		if _, ok := reflect.StructTag(tag).Lookup("mapstructure"); ok {
+			return true
+		}
+	}
+	return false
+}
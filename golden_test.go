@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+var update = flag.Bool("update", false, "Update the golden files of the metrics output")
+
+// TestGolden probes the simulated device with different options and
+// compares the exposition text against testdata/golden. Run with -update
+// after intended changes of the output.
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		options func(*wutconfig.Options)
+	}{
+		{"default", func(*wutconfig.Options) {}},
+		{"metric-names-both", func(o *wutconfig.Options) { o.MetricNames = wutconfig.MetricNamesBoth }},
+		{"error-values-metric", func(o *wutconfig.Options) { o.ErrorValues = wutconfig.ErrorValuesMetric }},
+		{"error-values-nan", func(o *wutconfig.Options) { o.ErrorValues = wutconfig.ErrorValuesNaN }},
+		{"integer-values", func(o *wutconfig.Options) {
+			o.IntegerValues = true
+			o.SensorLabels = wutconfig.SensorLabelsIndex
+			o.SensorIndexBase = 0
+		}},
+		{"profile-identity", func(o *wutconfig.Options) { o.Profiles = []string{"identity"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := startAgent(t, "public")
+			tt.options(&config.Options)
+			store := newConfigStore(config, zap.NewNop())
+
+			status, body := probe(t, probeHandler(store, nil, zap.NewNop()), "server", nil)
+			if status != http.StatusOK {
+				t.Fatalf("unexpected status %d: %s", status, body)
+			}
+
+			path := filepath.Join("testdata", "golden", tt.name+".prom")
+			if *update {
+				if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v, run with -update to create it", err)
+			}
+			if body != string(expected) {
+				t.Errorf("output differs from %s, run with -update if intended:\n%s", path, body)
+			}
+		})
+	}
+}
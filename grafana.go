@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// dashboardUID identifies the dashboard in Grafana, so that imports of
+// newer versions replace the previous one.
+const dashboardUID = "wut-temperature-exporter"
+
+// dashboardFamilies returns the metric families on the probe endpoint with
+// the active options and those currently exported on /metrics, so that the
+// dashboard follows the metric names and labels of the running code.
+func dashboardFamilies(config config) (probe, exporter []*dto.MetricFamily, err error) {
+	target := wutconfig.Target{IP: "192.0.2.1", Room: "dashboard"}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(staticCollector(config.collector(target, zap.NewNop()).SimulatedMetrics(time.Now())))
+	if probe, err = registry.Gather(); err != nil {
+		return nil, nil, err
+	}
+	if exporter, err = (prometheus.Gatherers{selfRegistry, collector.Registry}).Gather(); err != nil {
+		return nil, nil, err
+	}
+	exporter = slices.DeleteFunc(exporter, func(family *dto.MetricFamily) bool {
+		return !strings.HasPrefix(family.GetName(), "wut_") || strings.HasSuffix(family.GetName(), "_info")
+	})
+	return probe, exporter, nil
+}
+
+// dashboardPanel returns a time series panel of the family, graphing the
+// rate of counters and the 90th percentile of histograms.
+func dashboardPanel(family *dto.MetricFamily, id, x, y int) map[string]any {
+	var labels []string
+	if len(family.GetMetric()) > 0 {
+		for _, label := range family.GetMetric()[0].GetLabel() {
+			labels = append(labels, label.GetName())
+		}
+	}
+	selector := ""
+	if slices.Contains(labels, "room") {
+		selector = `{room=~"$room"}`
+	}
+	name := family.GetName()
+	var expr string
+	switch family.GetType() {
+	case dto.MetricType_COUNTER:
+		expr = fmt.Sprintf("rate(%s%s[$__rate_interval])", name, selector)
+	case dto.MetricType_HISTOGRAM:
+		expr = fmt.Sprintf("histogram_quantile(0.9, sum by (le) (rate(%s_bucket%s[$__rate_interval])))", name, selector)
+		labels = nil
+	default:
+		expr = name + selector
+	}
+	legend := "__auto"
+	if len(labels) > 0 {
+		parts := make([]string, len(labels))
+		for i, label := range labels {
+			parts[i] = "{{" + label + "}}"
+		}
+		legend = strings.Join(parts, " ")
+	}
+	return map[string]any{
+		"id":          id,
+		"type":        "timeseries",
+		"title":       name,
+		"description": family.GetHelp(),
+		"datasource":  map[string]any{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":     map[string]any{"x": x, "y": y, "w": 12, "h": 8},
+		"targets": []map[string]any{{
+			"refId":        "A",
+			"datasource":   map[string]any{"type": "prometheus", "uid": "${datasource}"},
+			"expr":         expr,
+			"legendFormat": legend,
+		}},
+	}
+}
+
+// dashboard returns a Grafana dashboard with a row of panels per group of
+// metric families.
+func dashboard(probe, exporter []*dto.MetricFamily) map[string]any {
+	var panels []map[string]any
+	id, y := 1, 0
+	for _, row := range []struct {
+		title    string
+		families []*dto.MetricFamily
+	}{{"Readings", probe}, {"Exporter", exporter}} {
+		panels = append(panels, map[string]any{
+			"id": id, "type": "row", "title": row.title, "collapsed": false,
+			"gridPos": map[string]any{"x": 0, "y": y, "w": 24, "h": 1},
+		})
+		id, y = id+1, y+1
+		for i, family := range row.families {
+			panels = append(panels, dashboardPanel(family, id, 12*(i%2), y+8*(i/2)))
+			id++
+		}
+		y += 8 * ((len(row.families) + 1) / 2)
+	}
+
+	// The rooms are listed from any family of the probe, all carry the
+	// room label.
+	room := "up"
+	if len(probe) > 0 {
+		room = probe[0].GetName()
+	}
+	return map[string]any{
+		"uid":           dashboardUID,
+		"title":         "WUT temperatures",
+		"tags":          []string{"wut", "temperature"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"refresh":       "1m",
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"templating": map[string]any{"list": []map[string]any{
+			{"name": "datasource", "type": "datasource", "query": "prometheus", "label": "Data source"},
+			{
+				"name": "room", "type": "query", "label": "Room",
+				"datasource": map[string]any{"type": "prometheus", "uid": "${datasource}"},
+				"query":      fmt.Sprintf("label_values(%s, room)", room),
+				"refresh":    2, "multi": true, "includeAll": true, "allValue": ".*",
+			},
+		}},
+		"panels": panels,
+	}
+}
+
+// dashboardHandler serves the Grafana dashboard of the exporter, generated
+// from the metric families exported with the active configuration.
+func dashboardHandler(store *configStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		probe, exporter, err := dashboardFamilies(store.Get())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, apiError{Code: errorCodeInternal, Message: err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(dashboard(probe, exporter))
+	}
+}
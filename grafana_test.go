@@ -0,0 +1,24 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestDashboardFollowsMetricNames(t *testing.T) {
+	config := config{Options: wutconfig.DefaultOptions()}
+	config.MetricNames = wutconfig.MetricNamesUnit
+	probe, exporter, err := dashboardFamilies(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, panel := range dashboard(probe, exporter)["panels"].([]map[string]any) {
+		titles = append(titles, panel["title"].(string))
+	}
+	if !slices.Contains(titles, "wut_temperature_celsius") || slices.Contains(titles, "wut_temperature") {
+		t.Errorf("expected a panel of wut_temperature_celsius only, got %v", titles)
+	}
+}
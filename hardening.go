@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// HardeningConfig restricts the privileges of the exporter after the
+// listeners are bound, for installations outside of containers. Changes
+// require a restart.
+type HardeningConfig struct {
+	// User is the name or UID of the user to switch to, e.g. to bind
+	// privileged ports like 162 for traps as root only.
+	User string `mapstructure:"user"`
+	// Group is the name or GID of the group to switch to. Defaults to the
+	// primary group of User.
+	Group string `mapstructure:"group"`
+	// RestrictFilesystem limits file access via Landlock to the files and
+	// directories of the configuration and the additional paths. Requires
+	// Linux 5.13 or later.
+	RestrictFilesystem bool `mapstructure:"restrict_filesystem"`
+	// ReadablePaths and WritablePaths are additional files and
+	// directories accessible with RestrictFilesystem.
+	ReadablePaths []string `mapstructure:"readable_paths"`
+	WritablePaths []string `mapstructure:"writable_paths"`
+}
+
+// systemReadablePaths are read by the standard library for name
+// resolution, outgoing TLS connections and the process metrics. Missing
+// paths are skipped.
+var systemReadablePaths = []string{
+	"/etc/hosts", "/etc/resolv.conf", "/etc/nsswitch.conf", "/etc/ssl", "/etc/pki",
+	"/etc/ca-certificates", "/usr/share/ca-certificates", "/usr/share/zoneinfo", "/proc/self",
+}
+
+// harden drops the privileges and restricts the file access of the process
+// as configured.
+func (c config) harden(logger *zap.Logger) error {
+	if c.Hardening.User != "" || c.Hardening.Group != "" {
+		uid, gid, err := c.Hardening.ids()
+		if err != nil {
+			return err
+		}
+		if err := dropPrivileges(uid, gid); err != nil {
+			return fmt.Errorf("error dropping privileges: %w", err)
+		}
+		logger.Info("Dropped privileges", zap.Int("uid", uid), zap.Int("gid", gid))
+	}
+	if c.Hardening.RestrictFilesystem {
+		readable, writable := c.accessedPaths()
+		readable, writable = existingPaths(readable), existingPaths(writable)
+		if err := restrictFilesystem(readable, writable); err != nil {
+			return fmt.Errorf("error restricting filesystem access: %w", err)
+		}
+		logger.Info("Restricted filesystem access", zap.Strings("readable", readable), zap.Strings("writable", writable))
+	}
+	return nil
+}
+
+// ids resolves the user and group to switch to.
+func (c HardeningConfig) ids() (uid, gid int, err error) {
+	uid, gid = os.Getuid(), os.Getgid()
+	if c.User != "" {
+		u, err := user.Lookup(c.User)
+		if err != nil {
+			if u, err = user.LookupId(c.User); err != nil {
+				return 0, 0, fmt.Errorf("unknown user %s", c.User)
+			}
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+		gid, _ = strconv.Atoi(u.Gid)
+	}
+	if c.Group != "" {
+		g, err := user.LookupGroup(c.Group)
+		if err != nil {
+			if g, err = user.LookupGroupId(c.Group); err != nil {
+				return 0, 0, fmt.Errorf("unknown group %s", c.Group)
+			}
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+	return uid, gid, nil
+}
+
+// accessedPaths returns the files and directories the configuration
+// requires access to. Directories are included for files that are reloaded,
+// as they are usually replaced rather than rewritten, and for the files
+// created next to the state file and databases.
+func (c config) accessedPaths() (readable, writable []string) {
+	readable = append(readable, systemReadablePaths...)
+	readable = append(readable, c.Hardening.ReadablePaths...)
+	if file := viper.ConfigFileUsed(); file != "" {
+		readable = appendDirs(readable, file)
+	}
+	if file := os.Getenv("SOPS_AGE_KEY_FILE"); file != "" {
+		readable = append(readable, file)
+	}
+	if configPublicKey != "" {
+		// The key is read again on every reload to verify the signature.
+		readable = append(readable, configPublicKey)
+	}
+	for _, listener := range c.Web.Listeners {
+		if listener.TLS != nil {
+			readable = appendDirs(readable, listener.TLS.CertFile, listener.TLS.KeyFile)
+			if listener.TLS.ClientCAFile != "" {
+				readable = append(readable, listener.TLS.ClientCAFile)
+			}
+		}
+	}
+
+	writable = append(writable, c.Hardening.WritablePaths...)
+	for _, file := range []string{c.StateFile, c.History.Path, c.HA.LeaseFile} {
+		if file != "" {
+			writable = appendDirs(writable, file)
+		}
+	}
+	return readable, writable
+}
+
+// appendDirs appends the directories of the files and of the targets of
+// symbolic links to them.
+func appendDirs(paths []string, files ...string) []string {
+	for _, file := range files {
+		paths = append(paths, filepath.Dir(file))
+		if target, err := filepath.EvalSymlinks(file); err == nil {
+			paths = append(paths, filepath.Dir(target))
+		}
+	}
+	return paths
+}
+
+// existingPaths returns the distinct paths that exist.
+func existingPaths(paths []string) []string {
+	var result []string
+	for _, path := range paths {
+		if slices.Contains(result, path) {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// restrictFilesystem is only supported on Linux.
+func restrictFilesystem(readable, writable []string) error {
+	return errors.New("restrict_filesystem is only supported on Linux")
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAccessedPathsPublicKey(t *testing.T) {
+	configPublicKey = "/etc/keys/wut.pub"
+	t.Cleanup(func() { configPublicKey = "" })
+
+	readable, _ := config{}.accessedPaths()
+	if !slices.Contains(readable, configPublicKey) {
+		t.Errorf("expected the public key to be readable, got %v", readable)
+	}
+}
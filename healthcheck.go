@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// healthcheckFlags are the flags of the healthcheck command.
+type healthcheckFlags struct {
+	healthURL   *string
+	timeout     *time.Duration
+	externalURL *string
+	prefix      *string
+}
+
+// newHealthcheckFlags defines the flags of the healthcheck command on the flag set.
+func newHealthcheckFlags(flags *pflag.FlagSet) healthcheckFlags {
+	return healthcheckFlags{
+		healthURL:   flags.String("url", "", "Health endpoint to query, derived from the web.listeners of the configuration file if not given"),
+		timeout:     flags.Duration("timeout", 3*time.Second, "Timeout of the health request"),
+		externalURL: flags.String("web.external-url", "", "--web.external-url of the exporter, to derive the route prefix from"),
+		prefix:      flags.String("web.route-prefix", "", "--web.route-prefix of the exporter"),
+	}
+}
+
+// runHealthcheck queries the health endpoint of a running exporter and
+// reports success via the exit code, for use as a container HEALTHCHECK.
+func runHealthcheck(args []string) int {
+	flags := pflag.NewFlagSet("healthcheck", pflag.ContinueOnError)
+	f := newHealthcheckFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *f.healthURL == "" {
+		external, err := parseExternalURL(*f.externalURL)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		*f.healthURL = loadWebConfig().healthURL(routePrefix(*f.prefix, external))
+		if *f.healthURL == "" {
+			fmt.Fprintln(os.Stderr, "No plain HTTP listener serves the health endpoint, pass --url")
+			return 2
+		}
+	}
+
+	if err := checkHealth(*f.healthURL, *f.timeout); err != nil {
+		fmt.Fprintf(os.Stderr, "Health check failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// loadWebConfig reads the web section of the configuration file, which is
+// all the health URL depends on. The file is neither verified nor
+// decrypted, so that the check stays cheap; pass --url if the credentials
+// of the listener are encrypted. Without a readable configuration the
+// default listener is queried.
+func loadWebConfig() WebConfig {
+	var web WebConfig
+	setupConfig()
+	if err := viper.ReadInConfig(); err != nil {
+		return web
+	}
+	viper.UnmarshalKey("web", &web)
+	return web
+}
+
+// checkHealth queries the given health endpoint and returns an error unless
+// it responds with 200 OK.
+func checkHealth(url string, timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
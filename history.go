@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// HistoryConfig configures the local history of readings.
+type HistoryConfig struct {
+	// Path of the SQLite database. The history is disabled if empty.
+	Path string `mapstructure:"path"`
+	// Retention is the age after which readings are deleted.
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS readings (
+	target    TEXT NOT NULL,
+	sensor    TEXT NOT NULL,
+	value     REAL NOT NULL,
+	unit      INTEGER NOT NULL,
+	timestamp INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS readings_target_timestamp ON readings (target, timestamp);
+`
+
+// historyStore records every reading of the background scrapes and pushes
+// in a SQLite database.
+type historyStore struct {
+	db        *sql.DB
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+// openHistory opens or creates the database of the history.
+func openHistory(config HistoryConfig, logger *zap.Logger) (*historyStore, error) {
+	db, err := sql.Open("sqlite", "file:"+config.Path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// Serialize writes, SQLite does not support concurrent writers.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &historyStore{db: db, retention: config.Retention, logger: logger}, nil
+}
+
+// Close closes the database.
+func (h *historyStore) Close() error {
+	return h.db.Close()
+}
+
+// Record stores the readings of the target. Errors are logged, as the
+// history must never affect the scrapes.
+func (h *historyStore) Record(target string, readings []collector.Reading) {
+	tx, err := h.db.Begin()
+	if err != nil {
+		h.logger.Error("Error recording history", zap.String("target", target), zap.Error(err))
+		return
+	}
+	defer tx.Rollback()
+	for _, reading := range readings {
+		_, err := tx.Exec("INSERT INTO readings (target, sensor, value, unit, timestamp) VALUES (?, ?, ?, ?, ?)",
+			target, reading.Sensor, reading.Value, reading.Unit, reading.Timestamp.UnixMilli())
+		if err != nil {
+			h.logger.Error("Error recording history", zap.String("target", target), zap.Error(err))
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		h.logger.Error("Error recording history", zap.String("target", target), zap.Error(err))
+	}
+}
+
+// Query returns the readings of the target taken between from and to,
+// ordered by time.
+func (h *historyStore) Query(ctx context.Context, target string, from, to time.Time) ([]collector.Reading, error) {
+	rows, err := h.db.QueryContext(ctx, "SELECT sensor, value, unit, timestamp FROM readings WHERE target = ? AND timestamp BETWEEN ? AND ? ORDER BY timestamp",
+		target, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var readings []collector.Reading
+	for rows.Next() {
+		var (
+			reading   collector.Reading
+			timestamp int64
+		)
+		if err := rows.Scan(&reading.Sensor, &reading.Value, &reading.Unit, &timestamp); err != nil {
+			return nil, err
+		}
+		reading.Timestamp = time.UnixMilli(timestamp)
+		readings = append(readings, reading)
+	}
+	return readings, rows.Err()
+}
+
+// Run deletes readings older than the retention every few minutes until
+// the context is cancelled.
+func (h *historyStore) Run(ctx context.Context) {
+	if h.retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for {
+		before := time.Now().Add(-h.retention).UnixMilli()
+		if _, err := h.db.ExecContext(ctx, "DELETE FROM readings WHERE timestamp < ?", before); err != nil && ctx.Err() == nil {
+			h.logger.Error("Error pruning history", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// historyPoint is a reading in responses of the history API.
+type historyPoint struct {
+	Sensor    string    `json:"sensor"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// historyHandler serves the recorded readings of the target passed in the
+// target parameter between the from and to parameters, given as RFC 3339
+// or Unix timestamps. They default to the last hour.
+func (h *historyStore) historyHandler(store *configStore, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r.Context(), fallback)
+		query := r.URL.Query()
+
+		target, ok := store.Get().findTarget(query.Get("target"))
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Target not found", Target: query.Get("target"), Hint: "pass the room or IP of a configured target"})
+			return
+		}
+		to, err := parseTime(query.Get("to"), time.Now())
+		if err != nil {
+			writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "Invalid 'to' parameter", Hint: "pass an RFC 3339 or Unix timestamp"})
+			return
+		}
+		from, err := parseTime(query.Get("from"), to.Add(-time.Hour))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "Invalid 'from' parameter", Hint: "pass an RFC 3339 or Unix timestamp"})
+			return
+		}
+
+		readings, err := h.Query(r.Context(), target.Name(), from, to)
+		if err != nil {
+			logger.Error("Error querying history", zap.String("target", target.Name()), zap.Error(err))
+			writeError(w, http.StatusInternalServerError, apiError{Code: errorCodeInternal, Message: "Error querying history", Target: target.Name()})
+			return
+		}
+		points := make([]historyPoint, 0, len(readings))
+		for _, reading := range readings {
+			points = append(points, historyPoint{Sensor: reading.Sensor, Value: reading.Value, Timestamp: reading.Timestamp})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Target   string         `json:"target"`
+			Readings []historyPoint `json:"readings"`
+		}{target.Name(), points})
+	}
+}
+
+// parseTime parses an RFC 3339 or Unix timestamp, returning the fallback
+// for empty values.
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.UnixMilli(int64(seconds * 1000)), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// HAConfig configures active/standby operation of multiple instances in
+// daemon mode. Only the instance holding the lease polls the targets.
+type HAConfig struct {
+	// LeaseFile is the lease shared by all instances, e.g. on a shared
+	// volume. HA is disabled if empty.
+	LeaseFile string `mapstructure:"lease_file"`
+	// LeaseDuration is the time after which the lease of a leader that
+	// stopped renewing it can be taken over.
+	LeaseDuration time.Duration `mapstructure:"lease_duration"`
+	// Identity identifies this instance in the lease. Defaults to the
+	// hostname.
+	Identity string `mapstructure:"identity"`
+}
+
+// lease is the content of the lease file.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// leaseLock elects a leader among instances sharing a lease file. The
+// leader renews the lease three times per lease duration, standbys take it
+// over once it expired. Instances read and write the lease only while
+// holding its lock file, so at most one of them takes over an expired
+// lease.
+//
+// A leader that cannot renew its lease for a whole lease duration, e.g.
+// because the shared volume hangs, only notices the loss on its next
+// renewal. Until then, for at most a third of the lease duration, both it
+// and the instance that took over scrape the targets.
+type leaseLock struct {
+	config HAConfig
+	logger *zap.Logger
+}
+
+func newLeaseLock(config HAConfig, logger *zap.Logger) *leaseLock {
+	if config.Identity == "" {
+		config.Identity, _ = os.Hostname()
+	}
+	return &leaseLock{config: config, logger: logger.With(zap.String("identity", config.Identity))}
+}
+
+// Run calls lead with a context cancelled on loss of the lease whenever
+// this instance becomes the leader. It blocks until the context is
+// cancelled and lead returned, and releases the lease on return.
+func (l *leaseLock) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(l.config.LeaseDuration / 3)
+	defer ticker.Stop()
+
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+	stop := func() {
+		if cancel == nil {
+			return
+		}
+		cancel()
+		<-done
+		cancel = nil
+		haLeader.Set(0)
+	}
+	defer func() {
+		stop()
+		if err := l.release(); err != nil {
+			l.logger.Warn("Error releasing lease", zap.Error(err))
+		}
+	}()
+
+	for {
+		leader, err := l.acquire(time.Now())
+		if err != nil {
+			l.logger.Error("Error acquiring lease", zap.String("lease_file", l.config.LeaseFile), zap.Error(err))
+		}
+		switch {
+		case leader && cancel == nil:
+			l.logger.Info("Acquired lease, starting background scrapes")
+			haLeader.Set(1)
+			cancel, done = startLeading(ctx, lead)
+		case !leader && cancel != nil:
+			l.logger.Warn("Lost lease, stopping background scrapes")
+			stop()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startLeading runs lead in the background with a cancelable context. The
+// returned channel is closed once lead returned.
+func startLeading(ctx context.Context, lead func(ctx context.Context)) (context.CancelFunc, chan struct{}) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(ctx)
+	}()
+	return cancel, done
+}
+
+// acquire takes or renews the lease and reports whether this instance
+// holds it.
+func (l *leaseLock) acquire(now time.Time) (bool, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	current, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	if current.Holder != l.config.Identity && now.Before(current.Expires) {
+		return false, nil
+	}
+	if err := l.write(lease{Holder: l.config.Identity, Expires: now.Add(l.config.LeaseDuration)}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// release removes the lease if this instance holds it, so that a standby
+// takes over without waiting for the lease to expire.
+func (l *leaseLock) release() error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, err := l.read()
+	if err != nil || current.Holder != l.config.Identity {
+		return err
+	}
+	return l.write(lease{Holder: l.config.Identity})
+}
+
+// errLeaseLocked reports that another instance held the lock file for
+// longer than a renewal period.
+var errLeaseLocked = errors.New("lease is locked by another instance")
+
+// lockRetry is the interval of the attempts to create the lock file.
+const lockRetry = 10 * time.Millisecond
+
+// lock exclusively creates the lock file next to the lease and returns the
+// function removing it. A lock file left behind by a crashed instance is
+// removed once it is older than the lease duration.
+func (l *leaseLock) lock() (func(), error) {
+	path := l.config.LeaseFile + ".lock"
+	deadline := time.Now().Add(l.config.LeaseDuration / 3)
+	for {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > l.config.LeaseDuration {
+			l.logger.Warn("Removing stale lease lock", zap.String("lock_file", path))
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, errLeaseLocked
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func (l *leaseLock) read() (lease, error) {
+	var current lease
+	data, err := os.ReadFile(l.config.LeaseFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return current, nil
+	}
+	if err != nil {
+		return current, err
+	}
+	if len(data) == 0 {
+		return current, nil
+	}
+	return current, json.Unmarshal(data, &current)
+}
+
+func (l *leaseLock) write(current lease) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.config.LeaseFile, data)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestLeaseLockFailover(t *testing.T) {
+	config := HAConfig{LeaseFile: filepath.Join(t.TempDir(), "leader.json"), LeaseDuration: 300 * time.Millisecond}
+	leading := make(chan string, 2)
+	run := func(ctx context.Context, identity string) {
+		config := config
+		config.Identity = identity
+		newLeaseLock(config, zap.NewNop()).Run(ctx, func(ctx context.Context) {
+			leading <- identity
+			<-ctx.Done()
+		})
+	}
+
+	ctx1, stop1 := context.WithCancel(t.Context())
+	done1 := make(chan struct{})
+	go func() {
+		run(ctx1, "a")
+		close(done1)
+	}()
+	if leader := <-leading; leader != "a" {
+		t.Fatalf("expected a to lead, got %s", leader)
+	}
+	go run(t.Context(), "b")
+
+	select {
+	case leader := <-leading:
+		t.Fatalf("%s leads while a holds the lease", leader)
+	case <-time.After(config.LeaseDuration):
+	}
+
+	stop1()
+	<-done1
+	select {
+	case leader := <-leading:
+		if leader != "b" {
+			t.Fatalf("expected b to take over, got %s", leader)
+		}
+	case <-time.After(2 * config.LeaseDuration):
+		t.Fatal("b did not take over")
+	}
+}
+
+func TestLeaseLockAcquireOnce(t *testing.T) {
+	config := HAConfig{LeaseFile: filepath.Join(t.TempDir(), "leader.json"), LeaseDuration: 3 * time.Second}
+	leaders := make(chan bool, 10)
+	var wg sync.WaitGroup
+	for i := range cap(leaders) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := config
+			config.Identity = strconv.Itoa(i)
+			leader, err := newLeaseLock(config, zap.NewNop()).acquire(time.Now())
+			if err != nil {
+				t.Error(err)
+			}
+			leaders <- leader
+		}()
+	}
+	wg.Wait()
+	close(leaders)
+
+	count := 0
+	for leader := range leaders {
+		if leader {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected a single leader, got %d", count)
+	}
+}
+
+func TestPollerHealthyStandby(t *testing.T) {
+	target := wutconfig.Target{IP: "192.0.2.1", Room: "server"}
+	p := newPoller(t.Context(), config{Targets: []wutconfig.Target{target}, ScrapeInterval: time.Minute}, zap.NewNop())
+	p.results[target.Name()] = collector.Result{Timestamp: time.Now().Add(-time.Hour)}
+
+	if err := p.Healthy(); err != nil {
+		t.Errorf("expected a standby with old results to be healthy, got %v", err)
+	}
+	p.setPolling(true)
+	if err := p.Healthy(); err == nil {
+		t.Error("expected an error for the stale target while polling")
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ListenerConfig configures one address the HTTP server listens on.
+type ListenerConfig struct {
+	// Address is the TCP address to listen on, e.g. "10.0.0.1:9191".
+	Address string `mapstructure:"address"`
+	// Paths restricts the listener to requests for one of the paths or
+	// the paths below them. All paths are served if it is empty.
+	Paths []string `mapstructure:"paths"`
+	// TLS enables HTTPS on the listener.
+	TLS *ListenerTLS `mapstructure:"tls"`
+	// BasicAuth requires HTTP basic authentication on the listener.
+	BasicAuth *BasicAuth `mapstructure:"basic_auth"`
+}
+
+// ListenerTLS configures the certificates of a TLS listener.
+type ListenerTLS struct {
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// ClientCAFile, if set, requires clients to present a certificate
+	// signed by one of the CAs in the file.
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// ClientCertOptional only verifies client certificates if presented,
+	// leaving it to route policies with client_cert to require them.
+	ClientCertOptional bool `mapstructure:"client_cert_optional"`
+}
+
+// BasicAuth holds the credentials of HTTP basic authentication.
+type BasicAuth struct {
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+}
+
+// listeners returns the configured listeners or a single listener on the
+// default address serving all paths.
+func (c WebConfig) listeners() []ListenerConfig {
+	if len(c.Listeners) == 0 {
+		return []ListenerConfig{{Address: listenAddress}}
+	}
+	return c.Listeners
+}
+
+// validate checks that every listener has an address and complete TLS and
+// authentication settings.
+func (c ListenerConfig) validate() error {
+	if c.Address == "" {
+		return errors.New("web.listeners require an address")
+	}
+	if c.TLS != nil && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return fmt.Errorf("tls of listener %s requires cert_file and key_file", c.Address)
+	}
+	if c.BasicAuth != nil && (c.BasicAuth.Username == "" || c.BasicAuth.Password == "") {
+		return fmt.Errorf("basic_auth of listener %s requires username and password", c.Address)
+	}
+	return nil
+}
+
+// check reports whether the request carries the credentials.
+func (b *BasicAuth) check(r *http.Request) bool {
+	username, password, ok := r.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(b.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(b.Password)) == 1
+}
+
+// handler restricts next to the paths and credentials of the listener.
+func (c ListenerConfig) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.serves(r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		if c.BasicAuth != nil {
+			if !c.BasicAuth.check(r) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="wut-temperature-exporter"`)
+				writeError(w, http.StatusUnauthorized, apiError{Code: errorCodeUnauthorized, Message: "Unauthorized"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serves reports whether the listener handles requests for the path.
+func (c ListenerConfig) serves(path string) bool {
+	if len(c.Paths) == 0 {
+		return true
+	}
+	for _, prefix := range c.Paths {
+		if hasPathPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether the prefix matches the path itself or the
+// paths below it, but not other paths starting with the same characters.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// tlsConfig loads the client CAs of the listener. The server certificate
+// is served by a certReloader.
+func (c ListenerConfig) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLS.ClientCAFile == "" {
+		return config, nil
+	}
+	pem, err := os.ReadFile(c.TLS.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CAs: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", c.TLS.ClientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if c.TLS.ClientCertOptional {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// serve starts serving the server on the listener address in the
+// background. TLS listeners serve the certificate of certs. Errors after a
+// successful start are passed to onError.
+func (c ListenerConfig) serve(server *http.Server, certs *certReloader, onError func(error)) error {
+	listener, err := net.Listen("tcp", c.Address)
+	if err != nil {
+		return err
+	}
+	if c.TLS == nil {
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				onError(err)
+			}
+		}()
+		return nil
+	}
+
+	server.TLSConfig, err = c.tlsConfig()
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	server.TLSConfig.GetCertificate = certs.GetCertificate
+	go func() {
+		if err := server.ServeTLS(listener, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			onError(err)
+		}
+	}()
+	return nil
+}
+
+// healthURL returns the URL of the health endpoint under the route prefix
+// on the first plain HTTP listener serving it, with the credentials of the
+// listener, or an empty string if there is none or a route policy requires
+// credentials for it.
+func (c WebConfig) healthURL(prefix string) string {
+	path := strings.TrimSuffix(prefix, "/") + "/healthz"
+	if policy, ok := c.policy(path); ok && policy.requiresCredentials() {
+		return ""
+	}
+	for _, listener := range c.listeners() {
+		if listener.TLS != nil || !listener.serves(path) {
+			continue
+		}
+		host, port, err := net.SplitHostPort(listener.Address)
+		if err != nil {
+			continue
+		}
+		if host == "" || net.ParseIP(host).IsUnspecified() {
+			host = "localhost"
+		}
+		u := url.URL{Scheme: "http", Host: net.JoinHostPort(host, port), Path: path}
+		if listener.BasicAuth != nil {
+			u.User = url.UserPassword(listener.BasicAuth.Username, listener.BasicAuth.Password)
+		}
+		return u.String()
+	}
+	return ""
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestListenerServes(t *testing.T) {
+	listener := ListenerConfig{Paths: []string{"/healthz", "/-/"}}
+	for path, expected := range map[string]bool{
+		"/healthz":    true,
+		"/healthz/":   true,
+		"/healthzfoo": false,
+		"/-/reload":   true,
+		"/-":          true,
+		"/metrics":    false,
+	} {
+		if got := listener.serves(path); got != expected {
+			t.Errorf("serves(%q) = %t, expected %t", path, got, expected)
+		}
+	}
+}
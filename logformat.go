@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerConfig returns the zap configuration of the --log-format. "json" is
+// meant for log collectors, "console" for reading the logs while debugging,
+// e.g. SNMP issues with --snmp-debug. Console logs are colored if written
+// to a terminal.
+func loggerConfig(format string) (zap.Config, error) {
+	switch format {
+	case "json":
+		return zap.NewProductionConfig(), nil
+	case "console":
+		config := zap.NewDevelopmentConfig()
+		// Keep the level and the handling of DPanic of production, the
+		// format is all that changes.
+		config.Level = zap.NewAtomicLevelAt(zap.InfoLevel)
+		config.Development = false
+		config.EncoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.DateTime + ".000")
+		if info, err := os.Stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		return config, nil
+	}
+	return zap.Config{}, fmt.Errorf("unknown log format %q, must be json or console", format)
+}
@@ -3,208 +3,600 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/gosnmp/gosnmp"
-
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/spf13/viper"
+	"github.com/spf13/pflag"
 	"go.uber.org/zap"
-)
 
-type Target struct {
-	IP   string `mapstructure:"ip"`
-	Room string `mapstructure:"room"`
-}
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
 
+// config is the configuration of the exporter.
 type config struct {
-	Targets   []Target
+	// ConfigVersion is the schema version of the file, see
+	// migrate-config.
+	ConfigVersion int `mapstructure:"config_version"`
+	// Targets are the devices probed by default.
+	Targets []wutconfig.Target
+	// Community is the SNMP community of the devices.
 	Community string
+	// ScrapeInterval is the interval of the background scrapes in daemon
+	// mode.
+	ScrapeInterval time.Duration `mapstructure:"scrape_interval"`
+	// ScrapeJitter spreads the background scrapes of the targets over this
+	// fraction of their interval instead of starting all at once.
+	ScrapeJitter float64 `mapstructure:"scrape_jitter"`
+	// MaxConcurrentScrapes is the number of workers running background
+	// scrapes, one per target if 0. Queued scrapes start by target
+	// priority.
+	MaxConcurrentScrapes int `mapstructure:"max_concurrent_scrapes"`
+	// MaxConcurrentProbes is the number of live probes scraping devices
+	// at the same time, unlimited if 0. Up to MaxQueuedProbes further
+	// probes wait for a free slot, others are rejected with 503. Changes
+	// require a restart.
+	MaxConcurrentProbes int `mapstructure:"max_concurrent_probes"`
+	MaxQueuedProbes     int `mapstructure:"max_queued_probes"`
+	// MaxAge is the age after which cached readings are stale. Stale
+	// readings are handled according to StaleReadings.
+	MaxAge time.Duration `mapstructure:"max_age"`
+	// StaleReadings selects whether stale readings are withheld or served
+	// and marked, see the staleReadings* constants.
+	StaleReadings string `mapstructure:"stale_readings"`
+	// History records all readings in a local database in daemon mode.
+	// Changes require a restart.
+	History HistoryConfig `mapstructure:"history"`
+	// AggregateWindow is the window of the minimum, maximum and average
+	// of the readings exported in daemon mode. Disabled if 0. Changes
+	// require a restart.
+	AggregateWindow time.Duration `mapstructure:"aggregate_window"`
+	// RoomAggregates exports the average, minimum and maximum of the
+	// latest readings of every room on /metrics in daemon mode. It
+	// requires NormalizeUnit. Changes require a restart.
+	RoomAggregates bool `mapstructure:"room_aggregates"`
+	// SmoothingAlpha is the smoothing factor of the moving average of the
+	// readings exported in daemon mode, between 0 (disabled) and 1.
+	SmoothingAlpha float64 `mapstructure:"smoothing_alpha"`
+	// RecentReadings is the number of readings of every sensor kept in
+	// memory and served on /history in daemon mode. Changes require a
+	// restart.
+	RecentReadings int `mapstructure:"recent_readings"`
+	// Webhook notifies about targets failing background scrapes.
+	Webhook WebhookConfig `mapstructure:"webhook"`
+	// HA runs multiple instances as active/standby in daemon mode.
+	// Changes require a restart.
+	HA HAConfig `mapstructure:"ha"`
+	// StateFile is the file the last readings are saved to on shutdown and
+	// restored from on startup in daemon mode. Disabled if empty.
+	StateFile string `mapstructure:"state_file"`
+	// AdminToken is the bearer token required by the administrative
+	// endpoints. They are disabled if neither a token nor OIDC is configured.
+	AdminToken string `mapstructure:"admin_token"`
+	// OIDC accepts tokens of an OpenID Connect provider on the
+	// administrative endpoints in addition to AdminToken.
+	OIDC OIDCConfig `mapstructure:"oidc"`
+	// TrapListenAddress is the UDP address SNMP traps are received on.
+	// The trap receiver is disabled if it is empty.
+	TrapListenAddress string `mapstructure:"trap_listen_address"`
+	// SyslogListenAddress is the UDP address syslog messages are received
+	// on. The syslog receiver is disabled if it is empty.
+	SyslogListenAddress string `mapstructure:"syslog_listen_address"`
+	// CanaryTarget is the target probed by /healthz/deep.
+	CanaryTarget string `mapstructure:"canary_target"`
+	// CanaryTimeout bounds the probe of the canary target.
+	CanaryTimeout time.Duration `mapstructure:"canary_timeout"`
+	// ProbeTimeout is the deadline of a probe. A shorter scrape timeout
+	// sent by Prometheus takes precedence.
+	ProbeTimeout time.Duration `mapstructure:"probe_timeout"`
+	// DrainTimeout is the time in-flight scrapes are given to finish on
+	// shutdown before they are cancelled.
+	DrainTimeout time.Duration `mapstructure:"drain_timeout"`
+	// SelfTest probes all targets once on startup and logs the results.
+	SelfTest bool `mapstructure:"self_test"`
+	// AlertThresholds are the default temperature limits of the rules
+	// emitted by generate-rules.
+	AlertThresholds wutconfig.Thresholds `mapstructure:"alert_thresholds"`
+	// FIPS fails the configuration unless the exporter runs in FIPS 140-3
+	// mode and only uses FIPS-approved algorithms.
+	FIPS bool `mapstructure:"fips"`
+	// Tenants are groups of targets probed on their own paths.
+	Tenants []TenantConfig `mapstructure:"tenants"`
+	// Hardening drops privileges after the listeners are bound. Changes
+	// require a restart.
+	Hardening HardeningConfig `mapstructure:"hardening"`
+	// Tracing exports the scrapes as OpenTelemetry spans. Changes require
+	// a restart.
+	Tracing TracingConfig `mapstructure:"tracing"`
+	// Web configures the HTTP server. Changes require a restart.
+	Web               WebConfig `mapstructure:"web"`
+	wutconfig.Options `mapstructure:",squash"`
+	// Simulate replaces all SNMP traffic with synthetic readings. It is
+	// set by the --simulate flag and not read from the config file.
+	Simulate bool `mapstructure:"-"`
+	// SNMPDebug lists the targets whose SNMP packets are traced, "all"
+	// traces every target. It is set by the --snmp-debug flag.
+	SNMPDebug []string `mapstructure:"-"`
+	// Shard restricts the targets to those of one of several instances.
+	// It is set by the --shard flag.
+	Shard shard `mapstructure:"-"`
 }
 
-type Collector struct {
-	Ip        string
-	Community string
-	Room      string
-	Logger    *zap.Logger
-}
-
-// Collect implements prometheus.Collector.
-func (c Collector) Collect(metrics chan<- prometheus.Metric) {
-	snmp := gosnmp.GoSNMP{}
-	snmp.Context = context.Background()
-	snmp.Community = c.Community
-	snmp.Version = gosnmp.Version1
-	snmp.Target = c.Ip
-	snmp.Port = 161
-	snmp.Transport = "udp"
-	snmp.Timeout = 3 * time.Second
-	snmp.MaxRepetitions = 50
-	snmp.Retries = 3
-	snmp.OnRetry = func(s *gosnmp.GoSNMP) {
-		c.Logger.Warn("SNMP retry", zap.String("ip", c.Ip))
-	}
-	err := snmp.Connect()
-	if err != nil {
-		c.Logger.Error("Error connecting to SNMP target", zap.String("ip", c.Ip), zap.Error(err))
-		return
+// validate checks the configuration for invalid settings.
+func (c config) validate() error {
+	if c.ConfigVersion > currentConfigVersion {
+		return fmt.Errorf("config_version %d is newer than the supported version %d, upgrade the exporter", c.ConfigVersion, currentConfigVersion)
 	}
-	defer snmp.Conn.Close()
-
-	data, err := snmp.WalkAll("1.3.6.1.4.1.5040.1.2.6.1.3.1.1")
-	if err != nil {
-		metric := prometheus.MustNewConstMetric(prometheus.NewDesc(
-			"up",
-			"WUT sensor status",
-			[]string{},
-			nil,
-		), prometheus.GaugeValue,
-			0,
-		)
-
-		metrics <- metric
-		c.Logger.Error("Error walking SNMP data", zap.String("ip", c.Ip), zap.Error(err))
-		return
+	if err := c.Options.Validate(); err != nil {
+		return err
 	}
-	labels, err := snmp.WalkAll("1.3.6.1.4.1.5040.1.2.6.3.2.1.1.1")
-	if err != nil {
-		metric := prometheus.MustNewConstMetric(prometheus.NewDesc(
-			"up",
-			"WUT sensor status",
-			[]string{},
-			nil,
-		), prometheus.GaugeValue,
-			0,
-		)
-
-		metrics <- metric
-		c.Logger.Error("Error walking SNMP labels", zap.String("ip", c.Ip), zap.Error(err))
-		return
+	if err := collector.ValidateProfiles(c.Profiles); err != nil {
+		return err
+	}
+	if err := collector.ValidateMetricHelp(c.MetricHelp); err != nil {
+		return err
 	}
-	for x, p := range data {
-		data := ""
-		switch p.Value.(type) {
-		case string:
-			data = p.Value.(string)
-		case []uint8:
-			data = string(p.Value.([]uint8))
+	names := make(map[string]wutconfig.Target, len(c.Targets))
+	for _, target := range c.Targets {
+		if err := target.Validate(); err != nil {
+			return err
 		}
-		if strings.Contains(data, "--") {
-			continue
+		name := strings.ToLower(target.Name())
+		if other, ok := names[name]; ok {
+			return fmt.Errorf("targets %s and %s are both named %s, set device to tell apart the devices of a room", other.IP, target.IP, target.Name())
 		}
-		label := ""
-		snmpLabel := labels[x]
-		switch snmpLabel.Value.(type) {
-		case string:
-			label = snmpLabel.Value.(string)
-		case []uint8:
-			label = string(snmpLabel.Value.([]uint8))
+		names[name] = target
+	}
+	if c.ScrapeInterval <= 0 {
+		return fmt.Errorf("invalid scrape_interval %s, must be positive", c.ScrapeInterval)
+	}
+	if c.RoomAggregates && !c.NormalizeUnit {
+		// Devices of a room may report different units, which only
+		// normalize_unit converts to a common one.
+		return fmt.Errorf("room_aggregates requires normalize_unit")
+	}
+	if c.MaxConcurrentScrapes < 0 {
+		return fmt.Errorf("invalid max_concurrent_scrapes %d, must not be negative", c.MaxConcurrentScrapes)
+	}
+	if c.MaxConcurrentProbes < 0 || c.MaxQueuedProbes < 0 {
+		return fmt.Errorf("invalid max_concurrent_probes %d or max_queued_probes %d, must not be negative", c.MaxConcurrentProbes, c.MaxQueuedProbes)
+	}
+	switch c.StaleReadings {
+	case staleReadingsWithhold, staleReadingsMark:
+	default:
+		return fmt.Errorf("invalid stale_readings %q, must be %s or %s", c.StaleReadings, staleReadingsWithhold, staleReadingsMark)
+	}
+	if c.HA.LeaseFile != "" && c.HA.LeaseDuration < 3*time.Second {
+		return fmt.Errorf("invalid ha lease_duration %s, must be at least 3s", c.HA.LeaseDuration)
+	}
+	if c.Webhook.URL != "" && c.Webhook.Failures < 1 {
+		return fmt.Errorf("invalid webhook failures %d, must be at least 1", c.Webhook.Failures)
+	}
+	if c.SmoothingAlpha < 0 || c.SmoothingAlpha > 1 {
+		return fmt.Errorf("invalid smoothing_alpha %g, must be between 0 and 1", c.SmoothingAlpha)
+	}
+	if c.ScrapeJitter < 0 || c.ScrapeJitter > 1 {
+		return fmt.Errorf("invalid scrape_jitter %g, must be between 0 and 1", c.ScrapeJitter)
+	}
+	if err := c.checkFIPS(); err != nil {
+		return err
+	}
+	if err := c.OIDC.validate(); err != nil {
+		return err
+	}
+	if err := c.Tracing.validate(); err != nil {
+		return err
+	}
+	if err := c.Web.Compression.validate(); err != nil {
+		return err
+	}
+	if c.CanaryTarget != "" {
+		if _, ok := c.findTarget(c.CanaryTarget); !ok {
+			return fmt.Errorf("canary_target %q is not a configured target", c.CanaryTarget)
 		}
+	}
+	for _, listener := range c.Web.Listeners {
+		if err := listener.validate(); err != nil {
+			return err
+		}
+	}
+	for _, route := range c.Web.Routes {
+		if err := route.validate(); err != nil {
+			return err
+		}
+	}
+	tenants := make(map[string]bool)
+	for _, tenant := range c.Tenants {
+		if err := tenant.validate(c.SensorLabel()); err != nil {
+			return err
+		}
+		if tenants[tenant.Name] {
+			return fmt.Errorf("duplicate tenant %s", tenant.Name)
+		}
+		tenants[tenant.Name] = true
+	}
+	return nil
+}
 
-		data = strings.TrimSpace(strings.ReplaceAll(data, ",", "."))
-
-		floatValue, err := strconv.ParseFloat(data, 32)
-		if err != nil {
-			continue
+// findTarget looks up a configured target by its room name or IP address.
+func (c config) findTarget(name string) (wutconfig.Target, bool) {
+	for _, x := range c.Targets {
+		if strings.EqualFold(x.Room, name) || strings.EqualFold(x.Name(), name) || x.IP == name {
+			return x, true
 		}
+	}
+	return wutconfig.Target{}, false
+}
 
-		metric := prometheus.MustNewConstMetric(prometheus.NewDesc(
-			"wut_temperature",
-			"Temperature reading from WUT sensor",
-			[]string{"room", "sensor"},
-			nil,
-		), prometheus.GaugeValue,
-			floatValue,
-			strings.ToLower(c.Room), label,
-		)
+// findTargets returns all targets of the room, or the single target with
+// the name or IP.
+func (c config) findTargets(name string) []wutconfig.Target {
+	var result []wutconfig.Target
+	for _, x := range c.Targets {
+		if strings.EqualFold(x.Room, name) {
+			result = append(result, x)
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+	if target, ok := c.findTarget(name); ok {
+		return []wutconfig.Target{target}
+	}
+	return nil
+}
 
-		metrics <- metric
+// collector returns the Collector used to scrape the given target. Its
+// logger is named after the target, which gives every log line of the
+// scrape the context of the target.
+func (c config) collector(target wutconfig.Target, logger *zap.Logger) collector.Collector {
+	result := collector.New(target, c.Community, c.Options, logger.Named(target.Name()))
+	if c.Simulate {
+		simulation := target.Simulation.WithDefaults()
+		result.Simulation = &simulation
 	}
+	result.SNMPDebug = slices.ContainsFunc(c.SNMPDebug, func(name string) bool {
+		return name == "all" || strings.EqualFold(name, target.Name()) || name == target.IP
+	})
+	return result
+}
+
+// listenAddress is the address the HTTP server listens on if no listeners
+// are configured.
+const listenAddress = ":9191"
 
+// command is a subcommand of the exporter.
+type command struct {
+	// run receives the remaining arguments and returns the process exit
+	// code.
+	run func(args []string) int
+	// flags defines the flags of the command, for completions and man
+	// pages. It is nil for commands without flags.
+	flags func(*pflag.FlagSet)
+	// summary describes the command in completions and man pages.
+	summary string
+	// args are the positional arguments after the flags, e.g. "[file]",
+	// and words lists the fixed ones offered as completions.
+	args  string
+	words []string
 }
 
-// Describe implements prometheus.Collector.
-func (c Collector) Describe(descs chan<- *prometheus.Desc) {
-	descs <- prometheus.NewDesc("wut_temperature", "", []string{"room", "sensor"}, prometheus.Labels{})
+// commands maps the names of subcommands to their entry points.
+var commands = map[string]command{
+	"healthcheck":    {run: runHealthcheck, flags: func(f *pflag.FlagSet) { newHealthcheckFlags(f) }, summary: "Query the health endpoint of a running exporter"},
+	"backfill":       {run: runBackfill, flags: func(f *pflag.FlagSet) { newBackfillFlags(f) }, summary: "Write the data logger history of a device to Prometheus"},
+	"generate-rules": {run: runGenerateRules, flags: func(f *pflag.FlagSet) { newRulesFlags(f) }, summary: "Print Prometheus alerting rules for the targets"},
+	"init":           {run: runInit, flags: func(f *pflag.FlagSet) { newInitFlags(f) }, summary: "Discover devices and write a configuration file"},
+	"migrate-config": {run: runMigrateConfig, flags: func(f *pflag.FlagSet) { newMigrateFlags(f) }, summary: "Upgrade a configuration file to the current schema", args: "[file]"},
+	"config":         {run: runConfig, summary: "Print an example configuration documenting every option", args: "example", words: []string{"example"}},
+	"doctor":         {run: runDoctor, flags: func(f *pflag.FlagSet) { newDoctorFlags(f) }, summary: "Diagnose a target step by step"},
+}
+
+// rootFlags are the flags of the exporter itself rather than of a
+// subcommand.
+type rootFlags struct {
+	once, simulate, daemon, printVersion                    *bool
+	pushGateway, externalURL, shard, routePrefix, logFormat *string
+	snmpDebug                                               *[]string
+}
+
+// newRootFlags defines the flags of the exporter on the flag set.
+func newRootFlags(flags *pflag.FlagSet) rootFlags {
+	f := rootFlags{
+		once:         flags.Bool("once", false, "Scrape all configured targets once, print the results and exit"),
+		pushGateway:  flags.String("push-gateway", "", "Push the results of --once to this Pushgateway URL instead of printing them"),
+		simulate:     flags.Bool("simulate", false, "Serve synthetic readings instead of querying the devices via SNMP"),
+		daemon:       flags.Bool("daemon", false, "Scrape all configured targets in the background and serve cached results"),
+		printVersion: flags.Bool("version", false, "Print version information and exit"),
+		externalURL:  flags.String("web.external-url", "", "URL the exporter is reachable at, e.g. behind a reverse proxy"),
+		snmpDebug:    flags.StringSlice("snmp-debug", nil, "Log packet-level SNMP traces of these targets by room or IP, or of all targets if none are given"),
+		shard:        flags.String("shard", "", "Only handle the targets of shard N/M (counted from 0) by hashing their address like a Prometheus hashmod relabeling, to split the targets between M instances"),
+		routePrefix:  flags.String("web.route-prefix", "", "Path prefix of all endpoints, defaults to the path of --web.external-url"),
+		logFormat:    flags.String("log-format", "json", "Log format, json or console for colored human-readable output"),
+	}
+	flags.Lookup("snmp-debug").NoOptDefVal = "all"
+	addPublicKeyFlag(flags)
+	return f
 }
 
 func main() {
-	logger, _ := zap.NewProduction()
-	defer logger.Sync()
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command.run(os.Args[2:]))
+		}
+	}
+
+	flags := newRootFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	if *flags.printVersion {
+		fmt.Println(currentVersion())
+		return
+	}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath("/etc/wut-temperature-exporter/")
-	viper.AddConfigPath(".")
-	err := viper.ReadInConfig()
+	logConfig, err := loggerConfig(*flags.logFormat)
 	if err != nil {
-		logger.Panic("No valid configuration found", zap.Error(err))
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	logLevel := logConfig.Level
+	logger, _ := logConfig.Build(zap.WrapCore(redaction.core))
+	defer logger.Sync()
 
-	config := config{}
+	externalURL, err := parseExternalURL(*flags.externalURL)
+	if err != nil {
+		logger.Fatal("Invalid web configuration", zap.Error(err))
+	}
+	prefix := routePrefix(*flags.routePrefix, externalURL)
 
-	err = viper.Unmarshal(&config)
+	setupConfig()
+	config, err := loadConfig()
 	if err != nil {
 		logger.Panic("No valid configuration found", zap.Error(err))
 	}
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		query := r.URL.Query()
+	config.Simulate = *flags.simulate
+	config.SNMPDebug = *flags.snmpDebug
+	config.Shard, err = parseShard(*flags.shard)
+	if err != nil {
+		logger.Fatal("Invalid shard", zap.Error(err))
+	}
+	config = config.sharded()
+	redaction.Reload(config)
+	if config.Shard.Count > 1 {
+		logger.Info("Handling shard of the targets", zap.Int("shard", config.Shard.Index), zap.Int("shards", config.Shard.Count), zap.Int("targets", len(config.Targets)))
+	}
+	shutdownTracing, err := setupTracing(config.Tracing)
+	if err != nil {
+		logger.Fatal("Invalid tracing configuration", zap.Error(err))
+	}
 
-		target := query.Get("target")
-		if len(query["target"]) != 1 || target == "" {
-			http.Error(w, "'target' parameter must be specified once", http.StatusBadRequest)
-			return
+	if *flags.once {
+		err = runOnce(config, *flags.pushGateway, logger)
+		shutdownTracing(context.Background())
+		if err != nil {
+			logger.Error("Batch scrape failed", zap.Error(err))
+			logger.Sync()
+			os.Exit(1)
 		}
+		return
+	}
 
-		registry := prometheus.NewRegistry()
-		h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	// ctx stops background work on shutdown, while scrapeCtx cancels
+	// in-flight scrapes once the drain timeout has passed.
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	scrapeCtx, cancelScrapes := context.WithCancel(context.Background())
+	defer cancelScrapes()
+
+	store := newConfigStore(config, logger)
+	store.OnReload(redaction.Reload)
+	updateTargetInfo(config)
+	store.OnReload(updateTargetInfo)
+	if config.SelfTest {
+		selfTest(ctx, config, logger)
+	}
 
-		room := ""
-		ip := ""
-		for _, x := range config.Targets {
-			if strings.EqualFold(x.Room, target) || x.IP == target {
-				room = x.Room
-				ip = x.IP
-				break
+	var poller *poller
+	pollerDone := make(chan struct{})
+	if *flags.daemon {
+		poller = newPoller(scrapeCtx, config, logger)
+		if config.StateFile != "" {
+			if err := poller.Restore(config.StateFile); err != nil {
+				logger.Warn("Error restoring last readings", zap.String("state_file", config.StateFile), zap.Error(err))
 			}
 		}
-
-		if ip == "" {
-			logger.Error("No target found", zap.String("target", target))
-			http.Error(w, "Not found", 404)
-			return
+		if config.History.Path != "" {
+			history, err := openHistory(config.History, logger)
+			if err != nil {
+				logger.Fatal("Error opening history", zap.String("path", config.History.Path), zap.Error(err))
+			}
+			defer history.Close()
+			go history.Run(ctx)
+			poller.recorders = append(poller.recorders, history)
+			http.Handle("/api/v1/history", history.historyHandler(store, logger))
+		}
+		if config.RecentReadings > 0 {
+			recent := newRecentReadings(config.RecentReadings)
+			poller.recorders = append(poller.recorders, recent)
+			http.Handle("/history", recent.recentHandler(store))
 		}
+		poller.aggregators = append(poller.aggregators, newSmoothing(store), newTemperatureRate())
+		poller.notifier = newWebhookNotifier(store, logger)
+		if config.AggregateWindow > 0 {
+			poller.aggregators = append(poller.aggregators, newWindowAggregates(config.AggregateWindow))
+		}
+		if config.RoomAggregates {
+			selfRegistry.MustRegister(roomAggregates{store: store, poller: poller})
+		}
+		store.OnReload(poller.Reload)
+		go func() {
+			defer close(pollerDone)
+			if config.HA.LeaseFile != "" {
+				newLeaseLock(config.HA, logger).Run(ctx, poller.Run)
+				return
+			}
+			poller.Run(ctx)
+		}()
+	}
 
-		c := Collector{Ip: ip, Room: room, Community: config.Community, Logger: logger}
-		registry.MustRegister(c)
-		h.ServeHTTP(w, r)
-	})
-	server := &http.Server{Addr: ":9191", Handler: nil}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
-		listenErr := server.ListenAndServe()
-		if listenErr != nil && !errors.Is(listenErr, http.ErrServerClosed) {
-			logger.Error("Error starting server", zap.Error(listenErr))
+		for range hup {
+			store.Reload()
 		}
 	}()
 
+	trapsListening := make(<-chan bool)
+	if config.TrapListenAddress != "" {
+		traps := newTrapReceiver(store, logger)
+		trapsListening = traps.listener.Listening()
+		go func() {
+			if err := traps.ListenAndServe(config.TrapListenAddress); err != nil {
+				logger.Error("Error starting SNMP trap listener", zap.Error(err))
+			}
+		}()
+		defer traps.Close()
+	}
+	if config.SyslogListenAddress != "" {
+		syslog, err := listenSyslog(config.SyslogListenAddress, store, logger)
+		if err != nil {
+			logger.Fatal("Error starting syslog listener", zap.Error(err))
+		}
+		go syslog.Serve()
+		defer syslog.Close()
+	}
+
+	if poller != nil {
+		http.HandleFunc("/push", pushHandler(store, poller, logger))
+		http.HandleFunc("/ui/{$}", uiHandler)
+		http.Handle("/api/v1/readings", poller.readingsHandler(store))
+	}
+	http.Handle("/metrics", promhttp.HandlerFor(prometheus.Gatherers{selfRegistry, collector.Registry}, promhttp.HandlerOpts{EnableOpenMetrics: true, DisableCompression: true}))
+	http.HandleFunc("/version", versionHandler)
+	http.Handle("/grafana/dashboard.json", dashboardHandler(store))
+	http.Handle("/-/reload", store.requireAdminToken(http.HandlerFunc(store.reloadHandler)))
+	http.Handle("/-/loglevel", store.requireAdminToken(logLevel))
+	http.Handle("/debug/walk", store.requireAdminToken(probeTimeout(store, walkHandler(store, logger))))
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+	http.Handle("/healthz/deep", deepHealthHandler(store, logger))
+	limiter := newProbeLimiter(store)
+	http.Handle("/probe/{tenant}", probeTimeout(store, limiter.handler(tenantProbeHandler(store, logger)).ServeHTTP))
+	if poller != nil {
+		// Cached results are served without scraping the devices.
+		http.Handle("/", probeTimeout(store, probeHandler(store, poller, logger)))
+	} else {
+		http.Handle("/", probeTimeout(store, limiter.handler(probeHandler(store, nil, logger)).ServeHTTP))
+	}
+	var servers []*http.Server
+	for _, listener := range config.Web.listeners() {
+		server := config.Web.newServer(listener, withRoutePrefix(prefix, http.DefaultServeMux))
+		// Log requests rejected by the listener as well.
+		server.Handler = accessLog(logger, traceContext(server.Handler))
+		server.BaseContext = func(net.Listener) context.Context { return scrapeCtx }
+		var certs *certReloader
+		if listener.TLS != nil {
+			var err error
+			certs, err = newCertReloader(listener.TLS, logger)
+			if err != nil {
+				logger.Fatal("Error starting server", zap.String("address", listener.Address), zap.Error(err))
+			}
+			go certs.Watch(ctx)
+		}
+		err := listener.serve(server, certs, func(err error) {
+			logger.Error("Error starting server", zap.String("address", listener.Address), zap.Error(err))
+		})
+		if err != nil {
+			logger.Fatal("Error starting server", zap.String("address", listener.Address), zap.Error(err))
+		}
+		logger.Info("Listening", zap.String("address", listener.Address), zap.Bool("tls", listener.TLS != nil), zap.String("route_prefix", prefix))
+		servers = append(servers, server)
+	}
+	if config.TrapListenAddress != "" {
+		// Privileges may be required to bind the trap port.
+		select {
+		case <-trapsListening:
+		case <-time.After(5 * time.Second):
+		}
+	}
+	if err := config.harden(logger); err != nil {
+		logger.Fatal("Error hardening the process", zap.Error(err))
+	}
+
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Warn("Error notifying systemd", zap.Error(err))
+	}
+	healthURL := config.Web.healthURL(prefix)
+	go watchdog(ctx, func() error {
+		if healthURL != "" {
+			if err := checkHealth(healthURL, 3*time.Second); err != nil {
+				return err
+			}
+		}
+		if poller != nil {
+			return poller.Healthy()
+		}
+		return nil
+	}, logger)
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
 
 	sig := <-interrupt
 	logger.Sugar().Infof("Shutting down server. Got signal: %v", sig)
+	sdNotify("STOPPING=1")
+	stop()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	// Wait for in-flight probes and background scrapes to finish before
+	// cancelling them.
+	drainCtx, cancel := context.WithTimeout(context.Background(), config.DrainTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Fatal("Server forced to shutdown:", zap.Error(err))
+	var wg sync.WaitGroup
+	errs := make([]error, len(servers))
+	for i, server := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = server.Shutdown(drainCtx)
+		}()
+	}
+	wg.Wait()
+	if poller != nil {
+		select {
+		case <-pollerDone:
+		case <-drainCtx.Done():
+		}
+	}
+	if drainCtx.Err() != nil {
+		logger.Warn("Drain timeout exceeded, cancelling in-flight scrapes", zap.Duration("drain_timeout", config.DrainTimeout))
+		cancelScrapes()
+		for _, server := range servers {
+			server.Close()
+		}
+	} else if err := errors.Join(errs...); err != nil {
+		logger.Error("Error shutting down server", zap.Error(err))
+	}
+	if poller != nil && config.StateFile != "" {
+		if err := poller.Save(config.StateFile); err != nil {
+			logger.Error("Error saving last readings", zap.String("state_file", config.StateFile), zap.Error(err))
+		}
+	}
+	if err := shutdownTracing(drainCtx); err != nil {
+		logger.Warn("Error flushing spans", zap.Error(err))
 	}
 	logger.Info("Server stopped")
 }
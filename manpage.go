@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// manFlags are the flags of the man command.
+type manFlags struct {
+	output *string
+}
+
+// newManFlags defines the flags of the man command on the flag set.
+func newManFlags(flags *pflag.FlagSet) manFlags {
+	return manFlags{
+		output: flags.StringP("output", "o", ".", "Directory to write the pages to"),
+	}
+}
+
+// runMan writes the man pages of the exporter and of every command,
+// generated from their flags, e.g. for packaging.
+func runMan(args []string) int {
+	flags := pflag.NewFlagSet("man", pflag.ContinueOnError)
+	f := newManFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	pages := map[string][]byte{program + ".1": manPage("")}
+	for _, name := range commandNames() {
+		pages[program+"-"+name+".1"] = manPage(name)
+	}
+	for file, page := range pages {
+		if err := os.WriteFile(filepath.Join(*f.output, file), page, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// manPage renders the page of the command, or of the exporter itself for
+// the empty name.
+func manPage(name string) []byte {
+	title, summary, synopsis := program, "Prometheus exporter for W&T Web-Thermometers", `\fB`+program+`\fR [\fIflags\fR]`
+	if name != "" {
+		cmd := commands[name]
+		title, summary = program+"-"+name, cmd.summary
+		synopsis = `\fB` + program + ` ` + name + `\fR [\fIflags\fR]`
+		if cmd.args != "" {
+			synopsis += ` \fI` + roffEscape(cmd.args) + `\fR`
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, ".TH %s 1 \"\" \"%s %s\"\n", strings.ToUpper(roffEscape(title)), program, roffEscape(version))
+	fmt.Fprintf(&out, ".SH NAME\n%s \\- %s\n", roffEscape(title), roffEscape(summary))
+	fmt.Fprintf(&out, ".SH SYNOPSIS\n%s\n", synopsis)
+	if name == "" {
+		fmt.Fprintf(&out, ".br\n\\fB%s\\fR \\fIcommand\\fR [\\fIflags\\fR]\n", program)
+		out.WriteString(".SH DESCRIPTION\nServes the readings of W&T Web-Thermometers queried via SNMP as Prometheus metrics. The devices and all options are configured in config.yaml, see \\fB" + program + " config example\\fR.\n")
+		out.WriteString(".SH COMMANDS\n")
+		for _, command := range commandNames() {
+			fmt.Fprintf(&out, ".TP\n\\fB%s\\fR\n%s, see \\fB%s-%s\\fR(1).\n", command, roffEscape(commands[command].summary), program, command)
+		}
+	}
+	if flags := visibleFlags(name); len(flags) > 0 {
+		out.WriteString(".SH OPTIONS\n")
+		for _, flag := range flags {
+			out.WriteString(".TP\n")
+			if flag.Shorthand != "" {
+				fmt.Fprintf(&out, "\\fB\\-%s\\fR, ", flag.Shorthand)
+			}
+			fmt.Fprintf(&out, "\\fB\\-\\-%s\\fR", roffEscape(flag.Name))
+			if takesValue(flag) {
+				fmt.Fprintf(&out, " \\fI%s\\fR", flag.Value.Type())
+			}
+			out.WriteString("\n" + roffEscape(flag.Usage))
+			if takesValue(flag) && flag.DefValue != "" && flag.DefValue != "[]" {
+				fmt.Fprintf(&out, " (default %s)", roffEscape(flag.DefValue))
+			}
+			out.WriteString(".\n")
+		}
+	}
+	if name != "" {
+		fmt.Fprintf(&out, ".SH SEE ALSO\n\\fB%s\\fR(1)\n", program)
+	}
+	return out.Bytes()
+}
+
+// roffEscape escapes backslashes and dashes, and control characters at the
+// start of the text.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// selfRegistry holds the exporter's own metrics, served on /metrics
+// independently of the probed targets.
+var selfRegistry = prometheus.NewRegistry()
+
+var buildInfo = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_exporter_build_info",
+	Help: "A metric with a constant '1' value labeled by version, commit, build date and Go version of the exporter.",
+}, []string{"version", "commit", "date", "goversion"})
+
+func init() {
+	selfRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	v := currentVersion()
+	buildInfo.WithLabelValues(v.Version, v.Commit, v.Date, v.GoVersion).Set(1)
+}
+
+var configReloadSuccess = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_config_last_reload_successful",
+	Help: "Whether the last configuration reload attempt was successful.",
+})
+
+var configReloadTimestamp = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_config_last_reload_timestamp_seconds",
+	Help: "Timestamp of the last successful configuration reload.",
+})
+
+var trapAlarms = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_trap_alarm_total",
+	Help: "Total number of alarm traps received from the WUT devices.",
+}, []string{"room", "alarm"})
+
+var trapAlarmTimestamp = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_trap_alarm_last_timestamp_seconds",
+	Help: "Unix timestamp of the last alarm trap received from the WUT devices.",
+}, []string{"room", "alarm"})
+
+var trapsDropped = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_traps_dropped_total",
+	Help: "Total number of received SNMP traps that were dropped.",
+}, []string{"reason"})
+
+var pushesReceived = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_pushes_received_total",
+	Help: "Total number of accepted pushes sent by the WUT devices.",
+}, []string{"target"})
+
+var pushesRejected = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_pushes_rejected_total",
+	Help: "Total number of pushes rejected for an invalid token.",
+}, []string{"target"})
+
+var pushAlarms = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_push_alarm_total",
+	Help: "Total number of alarms pushed by the WUT devices.",
+}, []string{"room", "alarm"})
+
+var syslogMessages = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_syslog_messages_total",
+	Help: "Total number of syslog messages received from the WUT devices.",
+}, []string{"room"})
+
+var syslogAlarmEvents = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_syslog_alarm_events_total",
+	Help: "Total number of alarm state changes reported by the WUT devices via syslog.",
+}, []string{"room", "alarm"})
+
+var syslogAlarmActive = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_syslog_alarm_active",
+	Help: "Whether the alarm was last reported as active via syslog.",
+}, []string{"room", "alarm"})
+
+var syslogDropped = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_syslog_messages_dropped_total",
+	Help: "Total number of received syslog messages that were dropped.",
+}, []string{"reason"})
+
+var selfTestSuccess = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_self_test_success",
+	Help: "Whether the target was reachable during the startup self-test.",
+}, []string{"target"})
+
+var selfTestSensors = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_self_test_sensors",
+	Help: "Number of valid sensor readings of the target during the startup self-test.",
+}, []string{"target"})
+
+var scrapeWorkers = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_scrape_workers",
+	Help: "Number of workers running background scrapes.",
+})
+
+var scrapeQueueDepth = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_scrape_queue_depth",
+	Help: "Number of due background scrapes waiting for a free worker.",
+})
+
+var scrapeQueueWait = promauto.With(selfRegistry).NewHistogram(prometheus.HistogramOpts{
+	Name:    "wut_scrape_queue_wait_seconds",
+	Help:    "Time due background scrapes waited for a free worker.",
+	Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+})
+
+var schedulerTargets = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_scheduler_targets",
+	Help: "Number of targets scheduled for background scrapes.",
+})
+
+var schedulerLag = promauto.With(selfRegistry).NewHistogram(prometheus.HistogramOpts{
+	Name:    "wut_scheduler_lag_seconds",
+	Help:    "Delay between the due time of background scrapes and their dispatch to the queue.",
+	Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+})
+
+var schedulerDispatched = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "wut_scheduler_dispatched_total",
+	Help: "Total number of background scrapes dispatched to the queue by the scheduler.",
+})
+
+var scrapesSkipped = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_scrapes_skipped_total",
+	Help: "Total number of due background scrapes skipped as the previous scrape of the target was still queued or running.",
+}, []string{"target"})
+
+var workerBusy = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_scrape_worker_busy",
+	Help: "Whether the worker is currently running a scrape.",
+}, []string{"worker"})
+
+var workerScrapes = promauto.With(selfRegistry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_scrape_worker_scrapes_total",
+	Help: "Total number of background scrapes run by the worker.",
+}, []string{"worker"})
+
+var haLeader = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_ha_leader",
+	Help: "Whether the instance holds the lease and runs the background scrapes.",
+})
+
+var probeQueueDepth = promauto.With(selfRegistry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_probe_queue_depth",
+	Help: "Number of live probes waiting for one of max_concurrent_probes slots.",
+})
+
+var probesRejected = promauto.With(selfRegistry).NewCounter(prometheus.CounterOpts{
+	Name: "wut_probes_rejected_total",
+	Help: "Total number of live probes rejected with 503 as max_concurrent_probes and max_queued_probes were reached.",
+})
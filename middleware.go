@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const loggerKey contextKey = iota
+
+// requestLogger returns the request scoped logger stored in the context by
+// accessLog, or fallback if there is none.
+func requestLogger(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.Logger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// newRequestID returns a random identifier for correlating log lines.
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder captures the status code and body size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// accessLog assigns a request ID to every request, makes a logger carrying
+// it available to downstream handlers and logs the request once it is done.
+// An X-Request-ID header sent by the client is reused.
+func accessLog(logger *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		reqLogger := logger.With(zap.String("request_id", id))
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(r.Context(), loggerKey, reqLogger)))
+
+		reqLogger.Info("HTTP request",
+			zap.String("client", r.RemoteAddr),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("target", r.URL.Query().Get("target")),
+			zap.Duration("duration", time.Since(start)),
+			zap.Int("status", recorder.status),
+			zap.Int("bytes", recorder.bytes),
+		)
+	})
+}
+
+// probeTimeout limits the context of the request to the configured probe
+// timeout, or the scrape timeout announced by Prometheus minus a small
+// margin if that is shorter. Handlers respond with 504 once it expires.
+func probeTimeout(store *configStore, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := store.Get().ProbeTimeout
+		if header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); header != "" {
+			if seconds, err := strconv.ParseFloat(header, 64); err == nil && seconds > 0 {
+				scrapeTimeout := time.Duration(seconds*float64(time.Second)) - probeTimeoutMargin
+				if scrapeTimeout > 0 && (timeout <= 0 || scrapeTimeout < timeout) {
+					timeout = scrapeTimeout
+				}
+			}
+		}
+		if timeout <= 0 {
+			next(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	})
+}
+
+// probeTimeoutMargin leaves time to write the response before Prometheus
+// gives up on the scrape.
+const probeTimeoutMargin = 500 * time.Millisecond
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// currentConfigVersion is the schema version of the configuration file
+// supported by this exporter. Files without config_version are of version
+// 1.
+const currentConfigVersion = 1
+
+// configMigration upgrades a configuration file to a schema version.
+type configMigration struct {
+	version int
+	// renames maps the dotted paths of keys moved in the version to their
+	// new paths.
+	renames map[string]string
+}
+
+// configMigrations are applied in order to files of older versions. The
+// list is empty as no keys have been moved since the schema was versioned;
+// keys renamed or removed by later versions have to be added here.
+var configMigrations []configMigration
+
+// migrateFlags are the flags of the migrate-config command.
+type migrateFlags struct {
+	inPlace *bool
+	check   *bool
+}
+
+// newMigrateFlags defines the flags of the migrate-config command on the flag set.
+func newMigrateFlags(flags *pflag.FlagSet) migrateFlags {
+	return migrateFlags{
+		inPlace: flags.BoolP("in-place", "i", false, "Replace the file, keeping the original as <file>.bak, instead of printing the migrated configuration"),
+		check:   flags.Bool("check", false, "Only report the changes and exit with 1 if the file needs to be migrated or has unknown keys"),
+	}
+}
+
+// runMigrateConfig upgrades a configuration file to the current schema
+// version and reports keys the exporter ignores.
+func runMigrateConfig(args []string) int {
+	flags := pflag.NewFlagSet("migrate-config", pflag.ContinueOnError)
+	f := newMigrateFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	file := "config.yaml"
+	if flags.NArg() > 0 {
+		file = flags.Arg(0)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	migrated, changes, err := migrateConfig(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating %s: %v\n", file, err)
+		return 1
+	}
+	unknown, err := unknownConfigKeys(migrated)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error checking %s: %v\n", file, err)
+		return 1
+	}
+	for _, change := range changes {
+		fmt.Fprintln(os.Stderr, change)
+	}
+	for _, key := range unknown {
+		fmt.Fprintf(os.Stderr, "Unknown key %s is ignored by the exporter\n", key)
+	}
+
+	switch {
+	case *f.check:
+		if len(changes) > 0 || len(unknown) > 0 {
+			return 1
+		}
+	case *f.inPlace:
+		if len(changes) == 0 {
+			return 0
+		}
+		if err := os.WriteFile(file+".bak", data, 0o600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := os.WriteFile(file, migrated, 0o600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if _, err := os.Stat(file + ".minisig"); err == nil {
+			fmt.Fprintf(os.Stderr, "Sign %s again, its signature is invalid now\n", file)
+		}
+	default:
+		os.Stdout.Write(migrated)
+	}
+	return 0
+}
+
+// migrateConfig applies the migrations of newer versions to the file,
+// keeping its comments, and returns the migrated file and the changes made.
+func migrateConfig(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("configuration is not a mapping")
+	}
+	root := doc.Content[0]
+
+	version := 1
+	if node := mappingValue(root, "config_version"); node != nil {
+		v, err := strconv.Atoi(node.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid config_version %q", node.Value)
+		}
+		version = v
+	}
+	if version > currentConfigVersion {
+		return nil, nil, fmt.Errorf("config_version %d is newer than the supported version %d", version, currentConfigVersion)
+	}
+
+	var changes []string
+	for _, migration := range configMigrations {
+		if migration.version <= version {
+			continue
+		}
+		for _, from := range slices.Sorted(maps.Keys(migration.renames)) {
+			if moveKey(root, strings.Split(from, "."), strings.Split(migration.renames[from], ".")) {
+				changes = append(changes, fmt.Sprintf("Moved deprecated key %s to %s", from, migration.renames[from]))
+			}
+		}
+	}
+	if node := mappingValue(root, "config_version"); node == nil {
+		root.Content = append([]*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "config_version", HeadComment: "Schema version of this file, see migrate-config."},
+			{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(currentConfigVersion)},
+		}, root.Content...)
+		changes = append(changes, fmt.Sprintf("Set config_version to %d", currentConfigVersion))
+	} else if version < currentConfigVersion {
+		node.Value = strconv.Itoa(currentConfigVersion)
+		changes = append(changes, fmt.Sprintf("Upgraded config_version from %d to %d", version, currentConfigVersion))
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	return out.Bytes(), changes, nil
+}
+
+// mappingValue returns the value of the key in the mapping node.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// moveKey moves the key at the path to the new path, creating missing
+// mappings. Keys already set at the new path are not overwritten.
+func moveKey(root *yaml.Node, from, to []string) bool {
+	parent := root
+	for _, key := range from[:len(from)-1] {
+		if parent = mappingValue(parent, key); parent == nil || parent.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	index := -1
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == from[len(from)-1] {
+			index = i
+		}
+	}
+	if index < 0 {
+		return false
+	}
+
+	target := root
+	for _, key := range to[:len(to)-1] {
+		next := mappingValue(target, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode}
+			target.Content = append(target.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			return false
+		}
+		target = next
+	}
+	if mappingValue(target, to[len(to)-1]) != nil {
+		return false
+	}
+	key, value := parent.Content[index], parent.Content[index+1]
+	parent.Content = slices.Delete(parent.Content, index, index+2)
+	key.Value = to[len(to)-1]
+	target.Content = append(target.Content, key, value)
+	return true
+}
+
+// unknownConfigKeys returns the keys of the file not decoded into the
+// configuration.
+func unknownConfigKeys(data []byte) ([]string, error) {
+	defer viper.Reset()
+	setupConfig()
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	var c config
+	var metadata mapstructure.Metadata
+	if err := viper.Unmarshal(&c, func(dc *mapstructure.DecoderConfig) { dc.Metadata = &metadata }); err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for _, key := range metadata.Unused {
+		// Field names of untagged fields are reported capitalized, keys
+		// are case-insensitive.
+		key = strings.ToLower(key)
+		if key != "sops" && !strings.HasPrefix(key, "sops.") {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown, nil
+}
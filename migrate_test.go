@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	configMigrations = []configMigration{{version: 1, renames: map[string]string{"listen": "web.address"}}}
+	t.Cleanup(func() { configMigrations = nil })
+
+	data := "config_version: 0\n# Address to listen on.\nlisten: \":9191\"\ntargets:\n  - ip: 10.0.0.1\n    rom: server\n"
+	migrated, changes, err := migrateConfig([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "config_version: 1\ntargets:\n  - ip: 10.0.0.1\n    rom: server\nweb:\n  # Address to listen on.\n  address: \":9191\"\n"
+	if string(migrated) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, migrated)
+	}
+	if len(changes) != 2 || !strings.Contains(changes[0], "listen to web.address") {
+		t.Errorf("unexpected changes %q", changes)
+	}
+
+	unknown, err := unknownConfigKeys(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(unknown, []string{"targets[0].rom", "web.address"}) {
+		t.Errorf("unexpected unknown keys %q", unknown)
+	}
+
+	if _, _, err := migrateConfig([]byte("config_version: 2\n")); err == nil {
+		t.Error("expected an error migrating a newer version")
+	}
+}
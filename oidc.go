@@ -0,0 +1,282 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig configures the validation of OpenID Connect ID or access
+// tokens accepted by the administrative endpoints.
+type OIDCConfig struct {
+	// Issuer is the URL of the identity provider. OIDC is disabled if it
+	// is empty.
+	Issuer string `mapstructure:"issuer"`
+	// Audience has to be contained in the aud claim of the tokens.
+	Audience string `mapstructure:"audience"`
+	// GroupsClaim is the claim listing the groups of the user.
+	GroupsClaim string `mapstructure:"groups_claim"`
+	// RequiredGroups lists the groups allowed to access the endpoints.
+	// Members of any of them are accepted, all users if it is empty.
+	RequiredGroups []string `mapstructure:"required_groups"`
+}
+
+// validate checks that tokens are restricted to an audience.
+func (c OIDCConfig) validate() error {
+	if c.Issuer != "" && c.Audience == "" {
+		return errors.New("oidc requires an audience")
+	}
+	return nil
+}
+
+// oidcLeeway is the clock skew tolerated when checking the validity of
+// tokens.
+const oidcLeeway = time.Minute
+
+// oidcKeyRefresh is the minimum interval between fetches of the signing
+// keys, bounding the requests to the issuer caused by unknown key IDs.
+const oidcKeyRefresh = time.Minute
+
+// oidcKeyMaxAge is the time the signing keys are cached, after which keys
+// rotated out or revoked by the issuer are no longer accepted.
+const oidcKeyMaxAge = time.Hour
+
+// oidcVerifier validates tokens signed by the keys of the issuer, which are
+// fetched via OIDC discovery and cached.
+type oidcVerifier struct {
+	client *http.Client
+
+	mu      sync.Mutex
+	issuer  string
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// adminOIDC verifies the tokens sent to the administrative endpoints.
+var adminOIDC = &oidcVerifier{client: &http.Client{Timeout: 10 * time.Second}}
+
+// Verify checks the signature and claims of the token.
+func (v *oidcVerifier) Verify(ctx context.Context, config OIDCConfig, token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return fmt.Errorf("malformed token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("malformed token signature: %w", err)
+	}
+	key, err := v.key(ctx, config.Issuer, header.Kid)
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return fmt.Errorf("malformed token claims: %w", err)
+	}
+	return config.checkClaims(claims, time.Now())
+}
+
+// checkClaims validates the issuer, audience, validity period and groups
+// of the token.
+func (c OIDCConfig) checkClaims(claims map[string]any, now time.Time) error {
+	if claims["iss"] != c.Issuer {
+		return fmt.Errorf("token issued by %v", claims["iss"])
+	}
+	if !slices.Contains(claimStrings(claims["aud"]), c.Audience) {
+		return errors.New("token not issued for the audience")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(oidcLeeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not valid yet")
+	}
+	if len(c.RequiredGroups) > 0 {
+		groups := claimStrings(claims[cmp.Or(c.GroupsClaim, "groups")])
+		if !slices.ContainsFunc(c.RequiredGroups, func(group string) bool { return slices.Contains(groups, group) }) {
+			return errors.New("user is not a member of the required groups")
+		}
+	}
+	return nil
+}
+
+// claimStrings returns a claim holding a string or an array of strings.
+func claimStrings(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []any:
+		var result []string
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature checks an RS256 or ES256 signature of the signed part of
+// a token.
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" {
+			break
+		}
+		if len(signature) != 64 {
+			return errors.New("invalid token signature")
+		}
+		r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported token algorithm %s", alg)
+}
+
+// key returns the signing key of the issuer with the ID, fetching the keys
+// again if it is unknown or the cached keys have expired.
+func (v *oidcVerifier) key(ctx context.Context, issuer, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.issuer != issuer {
+		v.issuer, v.keys, v.fetched = issuer, nil, time.Time{}
+	}
+	expired := time.Since(v.fetched) >= oidcKeyMaxAge
+	if key, ok := v.keys[kid]; ok && !expired {
+		return key, nil
+	}
+	if time.Since(v.fetched) < oidcKeyRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	keys, err := v.fetchKeys(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching signing keys: %w", err)
+	}
+	v.keys, v.fetched = keys, time.Now()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// fetchKeys discovers the JWKS of the issuer and returns its RSA and EC
+// keys by key ID.
+func (v *oidcVerifier) fetchKeys(ctx context.Context, issuer string) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document of %s is for issuer %s", issuer, discovery.Issuer)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			if fipsEnabled() && key.N.BitLen() < minFIPSRSABits {
+				// Keys this small are not FIPS-approved.
+				continue
+			}
+			keys[k.Kid] = key
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto"
+	"crypto/fips140"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// startIssuer serves the discovery document and JWKS of an identity
+// provider signing with the key.
+func startIssuer(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// signToken returns an RS256 token with the claims.
+func signToken(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := startIssuer(t, key)
+	config := OIDCConfig{Issuer: issuer, Audience: "wut-exporter", RequiredGroups: []string{"noc"}}
+	verifier := &oidcVerifier{client: http.DefaultClient}
+	claims := func(aud string, groups ...string) map[string]any {
+		return map[string]any{"iss": issuer, "aud": aud, "exp": time.Now().Add(time.Hour).Unix(), "groups": groups}
+	}
+
+	if err := verifier.Verify(t.Context(), config, signToken(t, key, claims("wut-exporter", "staff", "noc"))); err != nil {
+		t.Errorf("valid token rejected: %v", err)
+	}
+	if err := verifier.Verify(t.Context(), config, signToken(t, key, claims("other", "noc"))); err == nil {
+		t.Error("accepted token for another audience")
+	}
+	if err := verifier.Verify(t.Context(), config, signToken(t, key, claims("wut-exporter", "staff"))); err == nil {
+		t.Error("accepted token without the required group")
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if err := verifier.Verify(t.Context(), config, signToken(t, other, claims("wut-exporter", "noc"))); err == nil {
+		t.Error("accepted token with an invalid signature")
+	}
+}
+
+func TestOIDCKeyExpiry(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _ := rsa.GenerateKey(rand.Reader, 2048)
+	issuer := startIssuer(t, key)
+	config := OIDCConfig{Issuer: issuer, Audience: "wut-exporter"}
+	token := signToken(t, revoked, map[string]any{"iss": issuer, "aud": "wut-exporter", "exp": time.Now().Add(time.Hour).Unix()})
+
+	// The issuer no longer publishes the cached key.
+	verifier := &oidcVerifier{client: http.DefaultClient, issuer: issuer, keys: map[string]crypto.PublicKey{"test": &revoked.PublicKey}, fetched: time.Now()}
+	if err := verifier.Verify(t.Context(), config, token); err != nil {
+		t.Fatalf("token rejected before the keys expired: %v", err)
+	}
+	verifier.fetched = time.Now().Add(-oidcKeyMaxAge)
+	if err := verifier.Verify(t.Context(), config, token); err == nil {
+		t.Error("accepted token signed with a key revoked by the issuer")
+	}
+}
+
+func TestOIDCVerifyFIPS(t *testing.T) {
+	fipsEnabled = func() bool { return true }
+	t.Cleanup(func() { fipsEnabled = fips140.Enabled })
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := startIssuer(t, key)
+	config := OIDCConfig{Issuer: issuer, Audience: "wut-exporter"}
+	verifier := &oidcVerifier{client: http.DefaultClient}
+	claims := map[string]any{"iss": issuer, "aud": "wut-exporter", "exp": time.Now().Add(time.Hour).Unix()}
+
+	if err := verifier.Verify(t.Context(), config, signToken(t, key, claims)); err == nil {
+		t.Error("accepted token signed with a 1024 bit RSA key in FIPS mode")
+	}
+}
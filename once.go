@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// staticCollector replays a fixed set of previously scraped metrics.
+type staticCollector []prometheus.Metric
+
+// Collect implements prometheus.Collector.
+func (s staticCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, metric := range s {
+		metrics <- metric
+	}
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked as
+// the set of metrics depends on the scraped target.
+func (s staticCollector) Describe(chan<- *prometheus.Desc) {}
+
+// runOnce scrapes all configured targets sequentially and either prints the
+// results to stdout or pushes them to a Pushgateway. An error is returned if
+// any of the targets failed.
+func runOnce(config config, pushGateway string, logger *zap.Logger) error {
+	registry := prometheus.NewRegistry()
+	failed := 0
+	for _, target := range config.Targets {
+		c := config.collector(target, logger)
+		result := c.Scrape(context.Background())
+		if result.Err != nil {
+			logger.Error("Error scraping SNMP target", zap.String("ip", target.IP), zap.String("reason", collector.Reason(result.Err)), zap.Error(result.Err))
+			failed++
+		}
+		prometheus.WrapRegistererWith(prometheus.Labels{"target": target.Name()}, registry).MustRegister(staticCollector(c.Metrics(result)))
+	}
+
+	if pushGateway != "" {
+		err := push.New(pushGateway, "wut_temperature_exporter").Gatherer(registry).Push()
+		if err != nil {
+			return fmt.Errorf("pushing to %s: %w", pushGateway, err)
+		}
+	} else {
+		families, err := registry.Gather()
+		if err != nil {
+			return fmt.Errorf("gathering metrics: %w", err)
+		}
+		encoder := expfmt.NewEncoder(os.Stdout, expfmt.NewFormat(expfmt.TypeTextPlain))
+		for _, family := range families {
+			if err := encoder.Encode(family); err != nil {
+				return fmt.Errorf("writing metrics: %w", err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(config.Targets))
+	}
+	return nil
+}
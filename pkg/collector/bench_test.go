@@ -0,0 +1,84 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// benchmarkClient returns a mock device with the given number of named
+// channels reporting comma formatted values.
+func benchmarkClient(channels int) *MockClient {
+	pdus := make([]gosnmp.SnmpPDU, 0, 2*channels)
+	for i := 1; i <= channels; i++ {
+		pdus = append(pdus, octets(testValueOID+strconv.Itoa(i), fmt.Sprintf("%d,%d", 18+i%10, i%10)))
+	}
+	for i := 1; i <= channels; i++ {
+		pdus = append(pdus, octets(testLabelOID+strconv.Itoa(i), fmt.Sprintf("Rack %d", i)))
+	}
+	return &MockClient{PDUs: pdus}
+}
+
+// drain collects all metrics of the collector like a registry would.
+func drain(c prometheus.Collector) int {
+	metrics := make(chan prometheus.Metric, 256)
+	go func() {
+		c.Collect(metrics)
+		close(metrics)
+	}()
+	n := 0
+	for range metrics {
+		n++
+	}
+	return n
+}
+
+func BenchmarkCollect(b *testing.B) {
+	for _, channels := range []int{8, 16, 64} {
+		b.Run(fmt.Sprintf("channels=%d", channels), func(b *testing.B) {
+			c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+			c.Client = benchmarkClient(channels)
+			b.ReportAllocs()
+			for b.Loop() {
+				drain(c)
+			}
+		})
+	}
+}
+
+func BenchmarkCollectTargets(b *testing.B) {
+	for _, targets := range []int{100, 500} {
+		for _, channels := range []int{8, 64} {
+			b.Run(fmt.Sprintf("targets=%d/channels=%d", targets, channels), func(b *testing.B) {
+				client := benchmarkClient(channels)
+				collectors := make([]Collector, targets)
+				for i := range collectors {
+					collectors[i] = New(config.Target{IP: fmt.Sprintf("192.0.2.%d", i%250), Room: fmt.Sprintf("room-%d", i)}, "public", config.DefaultOptions(), zap.NewNop())
+					collectors[i].Client = client
+				}
+				b.ReportAllocs()
+				for b.Loop() {
+					for _, c := range collectors {
+						drain(c)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkParseValue(b *testing.B) {
+	for _, raw := range []string{"21,5", "1.234,5 °C"} {
+		b.Run(raw, func(b *testing.B) {
+			for b.Loop() {
+				ParseValue(raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+)
+
+// SNMPClient is the subset of gosnmp used to scrape a device. It allows
+// replacing the SNMP transport, e.g. with MockClient in tests.
+type SNMPClient interface {
+	Connect() error
+	Close() error
+	Get(oids []string) (*gosnmp.SnmpPacket, error)
+	// Walk passes the varbinds below the root OID to walkFn as they are
+	// received and stops at the first error it returns.
+	Walk(rootOid string, walkFn gosnmp.WalkFunc) error
+}
+
+// gosnmpClient adapts gosnmp.GoSNMP to SNMPClient.
+type gosnmpClient struct {
+	*gosnmp.GoSNMP
+}
+
+// Close closes the connection opened by Connect.
+func (c gosnmpClient) Close() error {
+	return c.Conn.Close()
+}
+
+// newClient returns the SNMPv1 client querying the device at the address,
+// counting the packets exchanged in the self-monitoring metrics. The
+// address may carry a port to query agents not listening on 161. Host
+// names are resolved via the DNS cache if DNSCacheTTL is set.
+//
+// The W&T devices are queried with SNMPv1, so there is no engine discovery
+// to cache. Should SNMPv3 be supported, the authoritative engine ID, boots
+// and time learned by gosnmp have to be kept per target across scrapes, as
+// every fresh client otherwise repeats the discovery round trip.
+func (c Collector) newClient(ctx context.Context, address string) SNMPClient {
+	snmp := &gosnmp.GoSNMP{}
+	snmp.Context = ctx
+	snmp.Community = c.Community
+	snmp.Version = gosnmp.Version1
+	snmp.Target = address
+	snmp.Port = 161
+	if host, port, err := net.SplitHostPort(address); err == nil {
+		if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+			snmp.Target, snmp.Port = host, uint16(p)
+		}
+	}
+	if c.DNSCacheTTL > 0 && net.ParseIP(snmp.Target) == nil {
+		// A failed lookup is left to gosnmp, which reports it on
+		// Connect.
+		if ip, err := resolved.lookup(ctx, snmp.Target, c.DNSCacheTTL); err == nil {
+			snmp.Target = ip
+		}
+	}
+	snmp.Transport = "udp"
+	snmp.Timeout = 3 * time.Second
+	snmp.MaxRepetitions = 50
+	snmp.Retries = 3
+	target := c.target()
+	snmp.OnSent = func(s *gosnmp.GoSNMP) {
+		snmpPacketsSent.WithLabelValues(target).Inc()
+	}
+	snmp.OnRecv = func(s *gosnmp.GoSNMP) {
+		snmpPacketsReceived.WithLabelValues(target).Inc()
+	}
+	snmp.OnRetry = func(s *gosnmp.GoSNMP) {
+		snmpTimeouts.WithLabelValues(target).Inc()
+		c.Logger.Warn("SNMP retry", zap.String("ip", address))
+	}
+	if c.SNMPDebug {
+		snmp.Logger = gosnmp.NewLogger(snmpLogger{c.Logger.With(zap.String("ip", address), zap.String("target", target))})
+	}
+	return gosnmpClient{snmp}
+}
+
+// HTTPHost returns the host of the SNMP address without its port, as used
+// in the URLs of the web server of the device.
+func HTTPHost(address string) string {
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// snmpLogger writes the debug output of gosnmp to zap.
+type snmpLogger struct {
+	logger *zap.Logger
+}
+
+func (l snmpLogger) Print(v ...any) {
+	l.logger.Info("SNMP trace", zap.String("trace", strings.TrimSuffix(fmt.Sprint(v...), "\n")))
+}
+
+func (l snmpLogger) Printf(format string, v ...any) {
+	l.logger.Info("SNMP trace", zap.String("trace", strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")))
+}
@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// systemDateOID is hrSystemDate of the HOST-RESOURCES-MIB.
+const systemDateOID = "1.3.6.1.2.1.25.1.2.0"
+
+// clockOffset returns the metric reporting the offset of the device clock
+// against the exporter clock. The clock is read via SNMP if the device
+// supports hrSystemDate and from the Date header of its web server at the
+// scraped address otherwise. Nil is returned if neither is available.
+func (c Collector) clockOffset(ctx context.Context, snmp SNMPClient, address string) prometheus.Metric {
+	offset, err := c.snmpClockOffset(snmp)
+	if err != nil {
+		c.Logger.Debug("Error reading device clock via SNMP", zap.String("ip", address), zap.Error(err))
+		offset, err = c.httpClockOffset(ctx, address)
+	}
+	if err != nil {
+		c.Logger.Debug("Error reading device clock via HTTP", zap.String("ip", address), zap.Error(err))
+		return nil
+	}
+
+	return prometheus.MustNewConstMetric(c.Desc(clockOffsetFamily), prometheus.GaugeValue,
+		offset.Seconds(),
+	)
+}
+
+// DeviceClockOffset reads the device clock like the ClockOffset option,
+// via SNMP or the web server of the device, and returns its offset against
+// the exporter clock.
+func (c Collector) DeviceClockOffset(ctx context.Context) (time.Duration, error) {
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, c.Ip)
+	}
+	if err := snmp.Connect(); err == nil {
+		defer snmp.Close()
+		if offset, err := c.snmpClockOffset(snmp); err == nil {
+			return offset, nil
+		}
+	}
+	return c.httpClockOffset(ctx, c.Ip)
+}
+
+func (c Collector) snmpClockOffset(snmp SNMPClient) (time.Duration, error) {
+	start := time.Now()
+	packet, err := snmp.Get([]string{systemDateOID})
+	if err != nil {
+		return 0, err
+	}
+	if len(packet.Variables) != 1 {
+		return 0, fmt.Errorf("unexpected number of variables")
+	}
+	value, ok := packet.Variables[0].Value.([]uint8)
+	if !ok {
+		return 0, fmt.Errorf("unsupported value %v", packet.Variables[0].Value)
+	}
+	deviceTime, err := parseDateAndTime(value)
+	if err != nil {
+		return 0, err
+	}
+	return deviceTime.Sub(midpoint(start, time.Now())), nil
+}
+
+// httpClockOffset reads the Date header of the web server of the device at
+// the SNMP address, within the deadline of the context.
+func (c Collector) httpClockOffset(ctx context.Context, address string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "http://"+HTTPHost(address)+"/", nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	deviceTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, err
+	}
+	// The Date header has a resolution of one second.
+	return deviceTime.Sub(midpoint(start, time.Now()).Truncate(time.Second)), nil
+}
+
+// midpoint returns the time halfway between start and end, the best
+// estimate of when the device read its clock.
+func midpoint(start, end time.Time) time.Time {
+	return start.Add(end.Sub(start) / 2)
+}
+
+// parseDateAndTime parses the DateAndTime textual convention of RFC 2579.
+// Devices omitting the time zone are assumed to run on UTC.
+func parseDateAndTime(b []byte) (time.Time, error) {
+	if len(b) != 8 && len(b) != 11 {
+		return time.Time{}, fmt.Errorf("invalid DateAndTime length %d", len(b))
+	}
+	location := time.UTC
+	if len(b) == 11 {
+		offset := (int(b[9])*60 + int(b[10])) * 60
+		if b[8] == '-' {
+			offset = -offset
+		}
+		location = time.FixedZone("", offset)
+	}
+	year := int(binary.BigEndian.Uint16(b[0:2]))
+	return time.Date(year, time.Month(b[2]), int(b[3]), int(b[4]), int(b[5]), int(b[6]), int(b[7])*int(100*time.Millisecond), location), nil
+}
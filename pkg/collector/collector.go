@@ -0,0 +1,477 @@
+// Package collector scrapes the temperature readings of W&T Web-Thermometers
+// via SNMP and exposes them as Prometheus metrics.
+//
+// A Collector scrapes a single device. It can be registered with a
+// prometheus.Registry directly, or used through Scrape to obtain the raw
+// readings and render them later via Metrics or Timestamped:
+//
+//	target := config.Target{IP: "192.0.2.10", Room: "server"}
+//	c := collector.New(target, "public", config.DefaultOptions(), zap.NewNop())
+//	result := c.Scrape(ctx)
+//
+// The self-monitoring metrics of all scrapes are collected in Registry.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// Collector scrapes a single WUT device. Logger must not be nil.
+type Collector struct {
+	Ip        string
+	Community string
+	Room      string
+	// Device tells apart several devices in the same room in the
+	// self-metrics, see config.Target.Device.
+	Device string
+	Logger *zap.Logger
+	config.Options
+	// Simulation, if set, generates synthetic readings instead of
+	// querying the device.
+	Simulation *config.Simulation
+	// Context cancels scrapes triggered via Collect. Defaults to
+	// context.Background().
+	Context context.Context
+	// Client, if set, replaces the SNMP connection to Ip, e.g. with a
+	// MockClient in tests.
+	Client SNMPClient
+	// Addresses are tried in order if the device cannot be reached at Ip,
+	// e.g. a secondary management address.
+	Addresses []string
+	// SNMPDebug logs packet-level traces of the SNMP exchange with the
+	// device.
+	SNMPDebug bool
+}
+
+// New returns the Collector for the target. Per-target bounds override
+// those of the options.
+func New(target config.Target, community string, options config.Options, logger *zap.Logger) Collector {
+	c := Collector{Ip: target.IP, Addresses: target.Addresses, Room: target.Room, Device: target.Device, Community: community, Options: options, Logger: logger}
+	if target.Bounds != nil {
+		c.Bounds = *target.Bounds
+	}
+	return c
+}
+
+// Reading is a single valid sensor reading.
+type Reading struct {
+	Sensor string
+	Value  float64
+	// Unit is one of the Unit* constants, which also tells temperatures
+	// apart from the readings of other sensor types.
+	Unit      int
+	Timestamp time.Time
+}
+
+// Result holds the outcome of a single scrape of a target.
+type Result struct {
+	// Metrics holds all metrics of the scrape except for the readings,
+	// which are rendered by Collector.Metrics.
+	Metrics   []prometheus.Metric
+	Readings  []Reading
+	Err       error
+	Timestamp time.Time
+}
+
+// Collect implements prometheus.Collector.
+func (c Collector) Collect(metrics chan<- prometheus.Metric) {
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result := c.Scrape(ctx)
+	if result.Err != nil {
+		c.Logger.Error("Error scraping SNMP target", zap.String("ip", c.Ip), zap.String("reason", Reason(result.Err)), zap.Error(result.Err))
+	}
+	for _, metric := range c.Metrics(result) {
+		metrics <- metric
+	}
+}
+
+// Metrics returns all metrics of the scrape result.
+func (c Collector) Metrics(result Result) []prometheus.Metric {
+	metrics := append([]prometheus.Metric{}, result.Metrics...)
+	for _, reading := range result.Readings {
+		metrics = append(metrics, c.reading(reading)...)
+	}
+	return append(metrics, c.derived(result.Readings, false)...)
+}
+
+// Timestamped returns all metrics of the scrape result carrying the time
+// they were collected, so that Prometheus records when a reading was
+// actually taken when serving cached results.
+func (c Collector) Timestamped(result Result) []prometheus.Metric {
+	metrics := make([]prometheus.Metric, 0, len(result.Metrics)+len(result.Readings))
+	for _, metric := range result.Metrics {
+		metrics = append(metrics, prometheus.NewMetricWithTimestamp(result.Timestamp, metric))
+	}
+	for _, reading := range result.Readings {
+		for _, metric := range c.reading(reading) {
+			metrics = append(metrics, prometheus.NewMetricWithTimestamp(reading.Timestamp, metric))
+		}
+	}
+	return append(metrics, c.derived(result.Readings, true)...)
+}
+
+// Scrape walks the sensor values of the target. On failure the metrics of
+// the result contain the "up" metric set to 0.
+func (c Collector) Scrape(ctx context.Context) Result {
+	scrapesInFlight.Inc()
+	defer scrapesInFlight.Dec()
+	ctx, span := tracer.Start(ctx, "scrape", trace.WithAttributes(attribute.String("target", c.target()), attribute.String("address", c.Ip)))
+	defer span.End()
+
+	result := Result{Timestamp: time.Now()}
+	if c.Simulation != nil {
+		result.Metrics, result.Readings = c.simulate(result.Timestamp)
+	} else {
+		result.Metrics, result.Readings, result.Err = c.walk(ctx, c.Ip, result.Timestamp)
+		for _, address := range c.Addresses {
+			if !failover(result.Err) || ctx.Err() != nil {
+				break
+			}
+			c.Logger.Warn("Error scraping SNMP target, trying next address", zap.String("ip", c.Ip), zap.String("address", address), zap.Error(result.Err))
+			addressFailovers.WithLabelValues(c.target()).Inc()
+			span.AddEvent("failover", trace.WithAttributes(attribute.String("address", address), attribute.String("error", result.Err.Error())))
+			result.Metrics, result.Readings, result.Err = c.walk(ctx, address, result.Timestamp)
+		}
+	}
+	observe(ctx, scrapeDuration.WithLabelValues(c.target()), time.Since(result.Timestamp).Seconds())
+	if result.Err == nil {
+		lastScrapeSuccess.WithLabelValues(c.target()).SetToCurrentTime()
+	} else {
+		inc(ctx, scrapeErrors.WithLabelValues(c.target(), Reason(result.Err)))
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, Reason(result.Err))
+	}
+	return result
+}
+
+// LabelOID is the table of the sensor names configured on the device.
+const LabelOID = "1.3.6.1.4.1.5040.1.2.6.3.2.1.1.1"
+
+// ValueOID returns the table of the sensor values, the integer branch with
+// IntegerValues and the string branch otherwise.
+func (c Collector) ValueOID() string {
+	if c.IntegerValues {
+		return "1.3.6.1.4.1.5040.1.2.6.1.4.1.1"
+	}
+	return "1.3.6.1.4.1.5040.1.2.6.1.3.1.1"
+}
+
+// walk queries the sensor values and labels from the device at the address
+// via SNMP.
+func (c Collector) walk(ctx context.Context, address string, now time.Time) ([]prometheus.Metric, []Reading, error) {
+	down := []prometheus.Metric{prometheus.MustNewConstMetric(c.Desc(upFamily), prometheus.GaugeValue,
+		0,
+	)}
+
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, address)
+	}
+	err := snmp.Connect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to SNMP target: %w", scrapeError{class: ErrConnect, err: err})
+	}
+	defer snmp.Close()
+	snmp = c.limit(snmp)
+
+	deviceUnit, unit := c.units(snmp)
+
+	valueOID := c.ValueOID()
+	// Walks failing after some varbinds were received still produce a
+	// partial result, which is exported and flagged via wut_scrape_partial.
+	partial, labelsPartial := false, false
+	labels, err := walkAll(snmp, LabelOID)
+	if err != nil {
+		if len(labels) == 0 {
+			return down, nil, fmt.Errorf("walking SNMP labels: %w", classify(err))
+		}
+		partial, labelsPartial = true, true
+		c.logPartialWalk(LabelOID, labels[len(labels)-1].Name, len(labels), err)
+	}
+	names := make(map[int]string, len(labels))
+	for _, snmpLabel := range labels {
+		switch snmpLabel.Value.(type) {
+		case string:
+			names[OIDIndex(snmpLabel.Name)] = snmpLabel.Value.(string)
+		case []uint8:
+			names[OIDIndex(snmpLabel.Name)] = string(snmpLabel.Value.([]uint8))
+		}
+	}
+
+	// The sensor values are converted to metrics as they are received
+	// rather than buffering the whole walk.
+	var sensors []prometheus.Metric
+	var readings []Reading
+	received, lastOID := 0, ""
+	parsed, unparsable := 0, 0
+	err = snmp.Walk(valueOID, func(p gosnmp.SnmpPDU) error {
+		received, lastOID = received+1, p.Name
+		data := ""
+		switch p.Value.(type) {
+		case string:
+			data = p.Value.(string)
+		case []uint8:
+			data = string(p.Value.([]uint8))
+		case int:
+			// The integer branch reports tenths of a degree.
+			data = strconv.FormatFloat(float64(p.Value.(int))/10, 'f', 1, 64)
+		}
+		index := OIDIndex(p.Name)
+		label, named := names[index]
+		if labelsPartial && !named && c.SensorLabels == config.SensorLabelsName {
+			// The name was lost in the failed walk, skip the sensor
+			// rather than exporting it under a different label.
+			return nil
+		}
+		if c.SensorLabels == config.SensorLabelsIndex || label == "" {
+			label = strconv.Itoa(index - 1 + c.SensorIndexBase)
+		}
+		channelUnit := c.channelUnit(index, data, unit)
+		if IsPlaceholder(data, c.ErrorTokens) {
+			// No probe is attached to this channel.
+			sensors = append(sensors, c.connected(label, false))
+			sensors = append(sensors, c.errorValue(label, "disconnected", channelUnit)...)
+			return nil
+		}
+		sensors = append(sensors, c.connected(label, true))
+
+		floatValue, err := ParseValue(data)
+		if err != nil {
+			parseFailures.WithLabelValues(c.target(), label).Inc()
+			c.Logger.Debug("Error parsing sensor value", zap.String("ip", c.Ip), zap.String("sensor", label), zap.String("value", data), zap.Error(err))
+			sensors = append(sensors, c.errorValue(label, "unparsable", channelUnit)...)
+			unparsable++
+			return nil
+		}
+		parsed++
+		if !IsTemperature(channelUnit) {
+			// The temperature unit and bounds do not apply to the other
+			// measurement types.
+			readings = append(readings, Reading{Sensor: label, Value: floatValue, Unit: channelUnit, Timestamp: now})
+			return nil
+		}
+		if c.NormalizeUnit {
+			floatValue = toCelsius(floatValue, deviceUnit)
+		}
+		if !c.Bounds.Contains(floatValue) {
+			outOfRange.WithLabelValues(c.target(), label).Inc()
+			c.Logger.Debug("Dropping implausible sensor value", zap.String("ip", c.Ip), zap.String("sensor", label), zap.Float64("value", floatValue))
+			return nil
+		}
+
+		readings = append(readings, Reading{Sensor: label, Value: floatValue, Unit: unit, Timestamp: now})
+		return nil
+	})
+	if err != nil {
+		if received == 0 {
+			return down, nil, fmt.Errorf("walking SNMP data: %w", classify(err))
+		}
+		partial = true
+		c.logPartialWalk(valueOID, lastOID, received, err)
+	}
+
+	result := []prometheus.Metric{c.partial(partial)}
+	if c.ClockOffset {
+		if metric := c.clockOffset(ctx, snmp, address); metric != nil {
+			result = append(result, metric)
+		}
+	}
+	result = append(result, c.collectProfiles(snmp)...)
+	result = append(result, sensors...)
+
+	if parsed == 0 && unparsable > 0 {
+		// Most likely a firmware formatting the values differently.
+		return result, readings, scrapeError{class: ErrParse, err: fmt.Errorf("parsing SNMP data: none of %d sensor values is a number", unparsable)}
+	}
+	return result, readings, nil
+}
+
+// ParseReading parses a sensor value received outside of a scrape, e.g.
+// pushed by the device, with temperatures in the unit of the device. The
+// value is converted and checked like during scrapes. Disconnected,
+// unparsable and implausible values are rejected and counted.
+func (c Collector) ParseReading(sensor, raw string, deviceUnit int, now time.Time) (Reading, bool) {
+	if IsPlaceholder(raw, c.ErrorTokens) {
+		return Reading{}, false
+	}
+	value, err := ParseValue(raw)
+	if err != nil {
+		parseFailures.WithLabelValues(c.target(), sensor).Inc()
+		c.Logger.Debug("Error parsing sensor value", zap.String("ip", c.Ip), zap.String("sensor", sensor), zap.String("value", raw), zap.Error(err))
+		return Reading{}, false
+	}
+	// The channel of a pushed value is unknown, which leaves the unit
+	// formatted into the value to tell the measurement types apart.
+	unit := c.channelUnit(0, raw, deviceUnit)
+	if !IsTemperature(unit) {
+		return Reading{Sensor: sensor, Value: value, Unit: unit, Timestamp: now}, true
+	}
+	if c.NormalizeUnit {
+		value, unit = toCelsius(value, deviceUnit), UnitCelsius
+	}
+	if !c.Bounds.Contains(value) {
+		outOfRange.WithLabelValues(c.target(), sensor).Inc()
+		return Reading{}, false
+	}
+	return Reading{Sensor: sensor, Value: value, Unit: unit, Timestamp: now}, true
+}
+
+// logPartialWalk logs a walk that failed after receiving some varbinds.
+func (c Collector) logPartialWalk(oid, lastOID string, received int, err error) {
+	c.Logger.Warn("SNMP walk failed mid-way, exporting partial result",
+		zap.String("ip", c.Ip),
+		zap.String("oid", oid),
+		zap.String("last_oid", lastOID),
+		zap.Int("received", received),
+		zap.Error(err),
+	)
+}
+
+// partial returns the metric flagging whether the scrape is incomplete.
+func (c Collector) partial(partial bool) prometheus.Metric {
+	value := 0.0
+	if partial {
+		value = 1
+	}
+	return prometheus.MustNewConstMetric(c.Desc(scrapePartialFamily), prometheus.GaugeValue,
+		value,
+	)
+}
+
+// OIDIndex returns the last component of an OID, which is the channel
+// number in the sensor tables of the devices.
+func OIDIndex(oid string) int {
+	index, _ := strconv.Atoi(oid[strings.LastIndex(oid, ".")+1:])
+	return index
+}
+
+// target returns the name identifying the scraped target in self-metrics.
+func (c Collector) target() string {
+	return config.Target{IP: c.Ip, Room: c.Room, Device: c.Device}.Name()
+}
+
+// RoomLabel returns the value of the room label.
+func (c Collector) RoomLabel() string {
+	if c.LowercaseLabels {
+		return strings.ToLower(c.Room)
+	}
+	return c.Room
+}
+
+// connected returns the metric reporting whether a probe is attached to the
+// sensor channel.
+func (c Collector) connected(sensor string, connected bool) prometheus.Metric {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	return prometheus.MustNewConstMetric(c.Desc(sensorConnectedFamily), prometheus.GaugeValue,
+		value,
+		c.RoomLabel(), sensor,
+	)
+}
+
+// errorValue returns the metrics exported for a sensor without a valid
+// reading according to the configured error value handling.
+func (c Collector) errorValue(sensor string, reason string, unit int) []prometheus.Metric {
+	switch c.ErrorValues {
+	case config.ErrorValuesNaN:
+		return c.reading(Reading{Sensor: sensor, Value: math.NaN(), Unit: unit})
+	case config.ErrorValuesMetric:
+		return []prometheus.Metric{prometheus.MustNewConstMetric(c.Desc(sensorErrorFamily), prometheus.GaugeValue,
+			1,
+			c.RoomLabel(), sensor, reason,
+		)}
+	default:
+		return nil
+	}
+}
+
+// reading returns the metrics of a reading in the family of its measurement
+// type.
+func (c Collector) reading(reading Reading) []prometheus.Metric {
+	return c.metrics(reading.Sensor, c.readingValues(reading))
+}
+
+// namedValue is a value of a reading in one of its metric families.
+type namedValue struct {
+	family Family
+	value  float64
+}
+
+// readingValues returns the values of a reading in the families of its
+// measurement type.
+func (c Collector) readingValues(reading Reading) []namedValue {
+	switch reading.Unit {
+	case UnitPercentRH:
+		return c.named(humidityFamily, &humidityPercentFamily, reading.Value, reading.Value)
+	case UnitHectopascal:
+		// Prometheus uses base units, the devices report hectopascals.
+		return c.named(pressureFamily, &pressurePascalsFamily, reading.Value, reading.Value*100)
+	case UnitAnalog:
+		// The unit of analog inputs is only known to the device.
+		return c.named(analogFamily, nil, reading.Value, reading.Value)
+	}
+	unitFamily, ok := temperatureUnitFamilies[reading.Unit]
+	if !ok {
+		unitFamily = temperatureUnitFamilies[UnitCelsius]
+	}
+	return c.named(temperatureFamily, &unitFamily, reading.Value, reading.Value)
+}
+
+// measurement returns the metrics of a single sensor reading, named
+// according to the configured metric naming.
+func (c Collector) measurement(legacy Family, unit *Family, sensor string, value, unitValue float64) []prometheus.Metric {
+	return c.metrics(sensor, c.named(legacy, unit, value, unitValue))
+}
+
+// named returns the values of a reading in the families of the configured
+// metric naming. The legacy family carries the value as reported by the
+// device, the family suffixed with the unit carries unitValue. Families
+// without a known unit only have the legacy name.
+func (c Collector) named(legacy Family, unit *Family, value, unitValue float64) []namedValue {
+	var result []namedValue
+	if c.MetricNames != config.MetricNamesUnit || unit == nil {
+		result = append(result, namedValue{legacy, value})
+	}
+	if c.MetricNames != config.MetricNamesLegacy && unit != nil {
+		result = append(result, namedValue{*unit, unitValue})
+	}
+	return result
+}
+
+// metrics returns the gauges of the values of the sensor.
+func (c Collector) metrics(sensor string, values []namedValue) []prometheus.Metric {
+	result := make([]prometheus.Metric, 0, len(values))
+	for _, v := range values {
+		result = append(result, prometheus.MustNewConstMetric(c.Desc(v.family), prometheus.GaugeValue,
+			v.value,
+			c.RoomLabel(), sensor,
+		))
+	}
+	return result
+}
+
+// Describe implements prometheus.Collector.
+func (c Collector) Describe(descs chan<- *prometheus.Desc) {
+	for _, family := range collectorFamilies() {
+		descs <- c.Desc(family)
+	}
+}
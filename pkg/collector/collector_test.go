@@ -0,0 +1,592 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+const (
+	testValueOID = ".1.3.6.1.4.1.5040.1.2.6.1.3.1.1."
+	testLabelOID = ".1.3.6.1.4.1.5040.1.2.6.3.2.1.1.1."
+)
+
+// staticMetrics collects a fixed set of metrics.
+type staticMetrics []prometheus.Metric
+
+func (staticMetrics) Describe(chan<- *prometheus.Desc) {}
+
+func (s staticMetrics) Collect(metrics chan<- prometheus.Metric) {
+	for _, metric := range s {
+		metrics <- metric
+	}
+}
+
+var errTest = errors.New("test error")
+
+func octets(oid, value string) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: []uint8(value)}
+}
+
+func str(oid, value string) gosnmp.SnmpPDU {
+	return gosnmp.SnmpPDU{Name: oid, Type: gosnmp.OctetString, Value: value}
+}
+
+func TestCollect(t *testing.T) {
+	tests := []struct {
+		name     string
+		pdus     []gosnmp.SnmpPDU
+		expected string
+	}{
+		{
+			name: "comma decimals",
+			pdus: []gosnmp.SnmpPDU{
+				octets(testValueOID+"1", "21,5"),
+				octets(testValueOID+"2", " -3,25 "),
+				octets(testLabelOID+"1", "Rack"),
+				octets(testLabelOID+"2", "Outside"),
+			},
+			expected: `
+# HELP wut_sensor_connected Whether a probe is connected to the WUT sensor channel
+# TYPE wut_sensor_connected gauge
+wut_sensor_connected{room="server",sensor="Outside"} 1
+wut_sensor_connected{room="server",sensor="Rack"} 1
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="Outside"} -3.25
+wut_temperature{room="server",sensor="Rack"} 21.5
+`,
+		},
+		{
+			name: "disconnected probe",
+			pdus: []gosnmp.SnmpPDU{
+				octets(testValueOID+"1", "22,0"),
+				octets(testValueOID+"2", "----"),
+				octets(testLabelOID+"1", "Rack"),
+				octets(testLabelOID+"2", "Spare"),
+			},
+			expected: `
+# HELP wut_sensor_connected Whether a probe is connected to the WUT sensor channel
+# TYPE wut_sensor_connected gauge
+wut_sensor_connected{room="server",sensor="Rack"} 1
+wut_sensor_connected{room="server",sensor="Spare"} 0
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="Rack"} 22
+`,
+		},
+		{
+			name: "mixed PDU types",
+			pdus: []gosnmp.SnmpPDU{
+				str(testValueOID+"1", "20.5"),
+				octets(testValueOID+"2", "18,5"),
+				octets(testLabelOID+"1", "Rack"),
+				str(testLabelOID+"2", "Door"),
+			},
+			expected: `
+# HELP wut_sensor_connected Whether a probe is connected to the WUT sensor channel
+# TYPE wut_sensor_connected gauge
+wut_sensor_connected{room="server",sensor="Door"} 1
+wut_sensor_connected{room="server",sensor="Rack"} 1
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="Door"} 18.5
+wut_temperature{room="server",sensor="Rack"} 20.5
+`,
+		},
+		{
+			name: "unnamed sensor",
+			pdus: []gosnmp.SnmpPDU{
+				octets(testValueOID+"1", "20,0"),
+				octets(testLabelOID+"1", ""),
+			},
+			expected: `
+# HELP wut_sensor_connected Whether a probe is connected to the WUT sensor channel
+# TYPE wut_sensor_connected gauge
+wut_sensor_connected{room="server",sensor="1"} 1
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="1"} 20
+`,
+		},
+		{
+			name: "mixed sensor types",
+			pdus: []gosnmp.SnmpPDU{
+				octets(testValueOID+"1", "21,5 °C"),
+				octets(testValueOID+"2", "45,2 %rH"),
+				octets(testValueOID+"3", "1013,2 hPa"),
+				octets(testLabelOID+"1", "Rack"),
+				octets(testLabelOID+"2", "Humidity"),
+				octets(testLabelOID+"3", "Pressure"),
+			},
+			expected: `
+# HELP wut_humidity Relative humidity reading from WUT sensor
+# TYPE wut_humidity gauge
+wut_humidity{room="server",sensor="Humidity"} 45.2
+# HELP wut_pressure Air pressure reading from WUT sensor
+# TYPE wut_pressure gauge
+wut_pressure{room="server",sensor="Pressure"} 1013.2
+# HELP wut_sensor_connected Whether a probe is connected to the WUT sensor channel
+# TYPE wut_sensor_connected gauge
+wut_sensor_connected{room="server",sensor="Humidity"} 1
+wut_sensor_connected{room="server",sensor="Pressure"} 1
+wut_sensor_connected{room="server",sensor="Rack"} 1
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="Rack"} 21.5
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(config.Target{IP: "192.0.2.1", Room: "Server"}, "public", config.DefaultOptions(), zap.NewNop())
+			c.Client = &MockClient{PDUs: tt.pdus}
+
+			err := testutil.CollectAndCompare(c, strings.NewReader(tt.expected), "wut_temperature", "wut_sensor_connected", "wut_humidity", "wut_pressure")
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCollectDeviceType(t *testing.T) {
+	options := config.DefaultOptions()
+	options.DeviceType = config.DeviceTypeThermoHygrobarometer
+	options.MetricNames = config.MetricNamesBoth
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5"),
+		octets(testValueOID+"2", "45,2"),
+		octets(testValueOID+"3", "1013,2"),
+		octets(testValueOID+"4", "19,0"),
+	}}
+
+	expected := `
+# HELP wut_humidity Relative humidity reading from WUT sensor
+# TYPE wut_humidity gauge
+wut_humidity{room="server",sensor="2"} 45.2
+# HELP wut_humidity_percent Relative humidity reading from WUT sensor
+# TYPE wut_humidity_percent gauge
+wut_humidity_percent{room="server",sensor="2"} 45.2
+# HELP wut_pressure Air pressure reading from WUT sensor
+# TYPE wut_pressure gauge
+wut_pressure{room="server",sensor="3"} 1013.2
+# HELP wut_pressure_pascals Air pressure reading from WUT sensor
+# TYPE wut_pressure_pascals gauge
+wut_pressure_pascals{room="server",sensor="3"} 101320
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="1"} 21.5
+wut_temperature{room="server",sensor="4"} 19
+`
+	names := []string{"wut_temperature", "wut_humidity", "wut_humidity_percent", "wut_pressure", "wut_pressure_pascals"}
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	options := config.DefaultOptions()
+	options.ErrorValues = config.ErrorValuesMetric
+	options.MetricHelp = map[string]string{"wut_temperature": "Temperature of the rack inlets"}
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5"),
+		octets(testValueOID+"2", "----"),
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+	}}
+
+	// The pedantic registry fails on metrics deviating from their
+	// descriptors, including the help texts.
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() == "wut_temperature" && family.GetHelp() != "Temperature of the rack inlets" {
+			t.Errorf("expected the configured help of wut_temperature, got %q", family.GetHelp())
+		}
+	}
+
+	if err := ValidateMetricHelp(map[string]string{"wut_temprature": "typo"}); err == nil {
+		t.Error("expected an error for an unknown family")
+	}
+}
+
+func TestSensorLabelName(t *testing.T) {
+	options := config.DefaultOptions()
+	options.SensorLabelName = "channel"
+	options.ErrorValues = config.ErrorValuesMetric
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5"),
+		octets(testValueOID+"2", "----"),
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+	}}
+
+	expected := `
+# HELP wut_sensor_error WUT sensor channel without a valid reading
+# TYPE wut_sensor_error gauge
+wut_sensor_error{channel="Door",reason="disconnected",room="server"} 1
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{channel="Rack",room="server"} 21.5
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "wut_sensor_error", "wut_temperature"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCollectErrorValues(t *testing.T) {
+	options := config.DefaultOptions()
+	options.ErrorValues = config.ErrorValuesMetric
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "----"),
+		octets(testValueOID+"2", "12 34"),
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+	}}
+
+	expected := `
+# HELP wut_sensor_error WUT sensor channel without a valid reading
+# TYPE wut_sensor_error gauge
+wut_sensor_error{reason="disconnected",room="server",sensor="Rack"} 1
+wut_sensor_error{reason="unparsable",room="server",sensor="Door"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "wut_sensor_error", "wut_temperature"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScrapeConnectError(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Client = &MockClient{ConnectErr: errTest}
+
+	result := c.Scrape(t.Context())
+	if !errors.Is(result.Err, ErrConnect) {
+		t.Fatalf("expected ErrConnect, got %v", result.Err)
+	}
+	if len(result.Readings) != 0 {
+		t.Errorf("expected no readings, got %v", result.Readings)
+	}
+}
+
+func TestScrapeWalkError(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Client = &MockClient{WalkErrs: map[string]error{"1.3.6.1.4.1.5040.1.2.6.1.3.1.1": errTest}}
+
+	result := c.Scrape(t.Context())
+	if Reason(result.Err) != "connect" {
+		t.Fatalf("expected connect error, got %v", result.Err)
+	}
+	expected := `
+# HELP up WUT sensor status
+# TYPE up gauge
+up 0
+`
+	if err := testutil.CollectAndCompare(staticMetrics(c.Metrics(result)), strings.NewReader(expected), "up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestScrapeDevicesOfRoom(t *testing.T) {
+	for _, device := range []string{"north", "south"} {
+		c := New(config.Target{IP: "192.0.2.1", Room: "devices", Device: device}, "public", config.DefaultOptions(), zap.NewNop())
+		c.Client = &MockClient{ConnectErr: errTest}
+		c.Scrape(t.Context())
+	}
+
+	for _, target := range []string{"devices/north", "devices/south"} {
+		if got := testutil.ToFloat64(scrapeErrors.WithLabelValues(target, "connect")); got != 1 {
+			t.Errorf("expected 1 scrape error of %s, got %v", target, got)
+		}
+	}
+}
+
+func TestScrapeExemplars(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "exemplars"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Client = &MockClient{WalkErrs: map[string]error{"1.3.6.1.4.1.5040.1.2.6.1.3.1.1": errTest}}
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	ctx := trace.ContextWithSpanContext(t.Context(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+	}))
+	c.Scrape(ctx)
+
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if !slices.ContainsFunc(metric.GetLabel(), func(label *dto.LabelPair) bool { return label.GetValue() == "exemplars" }) {
+				continue
+			}
+			exemplars := []*dto.Exemplar{metric.GetCounter().GetExemplar()}
+			for _, bucket := range metric.GetHistogram().GetBucket() {
+				exemplars = append(exemplars, bucket.GetExemplar())
+			}
+			for _, exemplar := range exemplars {
+				for _, label := range exemplar.GetLabel() {
+					if label.GetName() == "trace_id" && label.GetValue() == traceID.String() {
+						found[family.GetName()] = true
+					}
+				}
+			}
+		}
+	}
+	for _, name := range []string{"wut_scrape_duration_seconds", "wut_scrape_errors_total"} {
+		if !found[name] {
+			t.Errorf("expected an exemplar with the trace ID on %s", name)
+		}
+	}
+}
+
+func TestScrapeParseError(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "12 34"),
+		octets(testValueOID+"2", "----"),
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+	}}
+
+	if err := c.Scrape(t.Context()).Err; !errors.Is(err, ErrParse) {
+		t.Fatalf("expected ErrParse, got %v", err)
+	}
+}
+
+func TestScrapeMaxVarbinds(t *testing.T) {
+	options := config.DefaultOptions()
+	options.MaxVarbinds = 3
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+		octets(testValueOID+"1", "21.5"),
+		octets(testValueOID+"2", "19.0"),
+	}}
+
+	// The walk is truncated after the first value, which is still exported.
+	result := c.Scrape(t.Context())
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if len(result.Readings) != 1 || result.Readings[0].Sensor != "Rack" {
+		t.Errorf("expected only the reading of Rack, got %v", result.Readings)
+	}
+	expected := `
+# HELP wut_scrape_partial Whether the last scrape of the WUT sensor returned only partial results
+# TYPE wut_scrape_partial gauge
+wut_scrape_partial 1
+`
+	if err := testutil.CollectAndCompare(staticMetrics(c.Metrics(result)), strings.NewReader(expected), "wut_scrape_partial"); err != nil {
+		t.Error(err)
+	}
+
+	options.MaxVarbinds = 1
+	c.Options = options
+	if err := c.Scrape(t.Context()).Err; !errors.Is(err, ErrParse) {
+		t.Fatalf("expected ErrParse, got %v", err)
+	}
+}
+
+func TestParseReading(t *testing.T) {
+	min, max := -40.0, 100.0
+	options := config.DefaultOptions()
+	options.Bounds = config.Bounds{Min: &min, Max: &max}
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+
+	tests := []struct {
+		raw   string
+		value float64
+		ok    bool
+	}{
+		{"21,5", 21.5, true},
+		{" 19.0 ", 19, true},
+		{"----", 0, false},
+		{"abc", 0, false},
+		{"150", 0, false},
+	}
+	for _, tt := range tests {
+		reading, ok := c.ParseReading("rack", tt.raw, UnitCelsius, time.Time{})
+		if ok != tt.ok || reading.Value != tt.value {
+			t.Errorf("ParseReading(%q) = %v, %v, want %v, %v", tt.raw, reading.Value, ok, tt.value, tt.ok)
+		}
+	}
+
+	// Values of a device reporting Fahrenheit are normalized and checked
+	// against the bounds in Celsius.
+	if reading, ok := c.ParseReading("rack", "77", UnitFahrenheit, time.Time{}); !ok || reading.Value != 25 || reading.Unit != UnitCelsius {
+		t.Errorf("expected 25 °C for 77 °F, got %v, %v", reading, ok)
+	}
+	if _, ok := c.ParseReading("rack", "150", UnitFahrenheit, time.Time{}); !ok {
+		t.Error("expected 150 °F to lie within the bounds")
+	}
+	c.NormalizeUnit = false
+	if reading, ok := c.ParseReading("rack", "77", UnitFahrenheit, time.Time{}); !ok || reading.Value != 77 || reading.Unit != UnitFahrenheit {
+		t.Errorf("expected 77 °F as reported, got %v, %v", reading, ok)
+	}
+	if reading, ok := c.ParseReading("rack", "45 %", UnitFahrenheit, time.Time{}); !ok || reading.Unit != UnitPercentRH {
+		t.Errorf("expected a humidity reading, got %v, %v", reading, ok)
+	}
+}
+
+func TestDerivedMetrics(t *testing.T) {
+	// Reference values of the heat index chart of the National Weather
+	// Service and of psychrometric tables.
+	if index := heatIndex((90-32)*5/9.0, 70); math.Abs(index-(106-32)*5/9.0) > 0.5 {
+		t.Errorf("heat index of 90 °F at 70%% is %.2f °C, expected 106 °F", index)
+	}
+	if index := heatIndex(20, 50); math.Abs(index-19.6) > 0.5 {
+		t.Errorf("heat index of 20 °C at 50%% is %.2f °C, expected about the temperature", index)
+	}
+	if absolute := absoluteHumidity(20, 50); math.Abs(absolute-8.65) > 0.05 {
+		t.Errorf("absolute humidity of 20 °C at 50%% is %.2f g/m³, expected 8.65", absolute)
+	}
+
+	options := config.DefaultOptions()
+	options.DerivedMetrics = true
+	options.MetricNames = config.MetricNamesBoth
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5 °C"),
+		octets(testValueOID+"2", "45,2 %rH"),
+		octets(testValueOID+"3", "1013,2 hPa"),
+	}}
+	for _, name := range []string{"wut_heat_index", "wut_heat_index_celsius", "wut_absolute_humidity", "wut_absolute_humidity_grams_per_cubic_meter"} {
+		if count := testutil.CollectAndCount(c, name); count != 1 {
+			t.Errorf("expected one %s, got %d", name, count)
+		}
+	}
+
+	// Fahrenheit readings exported as reported are converted for the
+	// derived metrics.
+	options.NormalizeUnit = false
+	options.MetricNames = config.MetricNamesLegacy
+	c = New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(unitOID, "°F"),
+		octets(testValueOID+"1", "90,0"),
+		octets(testValueOID+"2", "70 %rH"),
+	}}
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := slices.IndexFunc(families, func(family *dto.MetricFamily) bool { return family.GetName() == "wut_heat_index" })
+	if index < 0 {
+		t.Fatal("missing wut_heat_index")
+	}
+	if value := families[index].GetMetric()[0].GetGauge().GetValue(); math.Abs(value-(106-32)*5/9.0) > 0.5 {
+		t.Errorf("heat index of a device reporting 90 °F at 70%% is %.2f °C, expected 106 °F", value)
+	}
+}
+
+func TestSimulateFlat(t *testing.T) {
+	zero := 0.0
+	simulation := config.Simulation{Sensors: []string{"Rack 1", "Rack 2"}, Base: &zero, Amplitude: &zero, Noise: &zero}.WithDefaults()
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Simulation = &simulation
+
+	expected := `
+# HELP wut_temperature Temperature reading from WUT sensor
+# TYPE wut_temperature gauge
+wut_temperature{room="server",sensor="Rack 1"} 0
+wut_temperature{room="server",sensor="Rack 2"} 0.5
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "wut_temperature"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDiagnosticsProfile(t *testing.T) {
+	options := config.DefaultOptions()
+	options.Profiles = []string{"diagnostics"}
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", options, zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5"),
+		{Name: diagnosticsOID + ".1.0", Type: gosnmp.Counter32, Value: uint(3)},
+		{Name: diagnosticsOID + ".2.0", Type: gosnmp.Gauge32, Value: uint(7)},
+	}}
+
+	expected := `
+# HELP wut_device_diagnostic_errors_total Internal error and sensor bus error counters of the WUT device
+# TYPE wut_device_diagnostic_errors_total counter
+wut_device_diagnostic_errors_total{counter="1.0",room="server"} 3
+# HELP wut_device_diagnostic_value Numeric values of the WUT device diagnostics that are not counters
+# TYPE wut_device_diagnostic_value gauge
+wut_device_diagnostic_value{room="server",value="2.0"} 7
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "wut_device_diagnostic_errors_total", "wut_device_diagnostic_value"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHTTPHost(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1":        "192.0.2.1",
+		"192.0.2.1:1161":   "192.0.2.1",
+		"wut.example.edu":  "wut.example.edu",
+		"2001:db8::1":      "[2001:db8::1]",
+		"[2001:db8::1]:16": "[2001:db8::1]",
+	}
+	for address, expected := range tests {
+		if got := HTTPHost(address); got != expected {
+			t.Errorf("%s: expected %s, got %s", address, expected, got)
+		}
+	}
+}
+
+func TestHTTPClockOffsetContext(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "server"}, "public", config.DefaultOptions(), zap.NewNop())
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := c.httpClockOffset(ctx, c.Ip); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancelled context to abort the request, got %v", err)
+	}
+}
+
+func TestScrapeUnknownPlaceholder(t *testing.T) {
+	c := New(config.Target{IP: "192.0.2.1", Room: "placeholders"}, "public", config.DefaultOptions(), zap.NewNop())
+	c.Client = &MockClient{PDUs: []gosnmp.SnmpPDU{
+		octets(testValueOID+"1", "21,5"),
+		octets(testValueOID+"2", "ERR"),
+		octets(testValueOID+"3", "--"),
+		octets(testLabelOID+"1", "Rack"),
+		octets(testLabelOID+"2", "Door"),
+		octets(testLabelOID+"3", "Floor"),
+	}}
+	c.Scrape(t.Context())
+
+	if got := testutil.ToFloat64(parseFailures.WithLabelValues("placeholders", "Door")); got != 1 {
+		t.Errorf("expected a parse failure of Door, got %v", got)
+	}
+	if got := testutil.ToFloat64(parseFailures.WithLabelValues("placeholders", "Floor")); got != 0 {
+		t.Errorf("expected no parse failure for the placeholder of Floor, got %v", got)
+	}
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"math"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Families derived from temperature and humidity readings.
+var (
+	heatIndexFamily             = NewFamily("wut_heat_index", "Heat index in degrees Celsius derived from WUT sensor temperature and humidity", "room", "sensor")
+	heatIndexCelsiusFamily      = NewFamily("wut_heat_index_celsius", "Heat index derived from WUT sensor temperature and humidity", "room", "sensor")
+	absoluteHumidityFamily      = NewFamily("wut_absolute_humidity", "Absolute humidity in g/m³ derived from WUT sensor temperature and humidity", "room", "sensor")
+	absoluteHumidityGramsFamily = NewFamily("wut_absolute_humidity_grams_per_cubic_meter", "Absolute humidity derived from WUT sensor temperature and humidity", "room", "sensor")
+)
+
+// derived returns the heat index and absolute humidity of every humidity
+// reading combined with the temperature reading before it, or the first
+// one of the device, labelled with the humidity sensor. Timestamped
+// metrics carry the time of the humidity reading.
+func (c Collector) derived(readings []Reading, timestamped bool) []prometheus.Metric {
+	if !c.DerivedMetrics {
+		return nil
+	}
+	first := -1
+	for i, reading := range readings {
+		if IsTemperature(reading.Unit) {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+
+	var metrics []prometheus.Metric
+	temperature := readings[first]
+	for _, reading := range readings {
+		if IsTemperature(reading.Unit) {
+			temperature = reading
+			continue
+		}
+		if reading.Unit != UnitPercentRH {
+			continue
+		}
+		celsius := toCelsius(temperature.Value, temperature.Unit)
+		index, absolute := heatIndex(celsius, reading.Value), absoluteHumidity(celsius, reading.Value)
+		derived := append(
+			c.measurement(heatIndexFamily, &heatIndexCelsiusFamily, reading.Sensor, index, index),
+			c.measurement(absoluteHumidityFamily, &absoluteHumidityGramsFamily, reading.Sensor, absolute, absolute)...,
+		)
+		for _, metric := range derived {
+			if timestamped {
+				metric = prometheus.NewMetricWithTimestamp(reading.Timestamp, metric)
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// heatIndex returns the apparent temperature in degrees Celsius using the
+// regression of the US National Weather Service, which works in degrees
+// Fahrenheit. Below a heat index of 80 °F the simpler formula of Steadman
+// is used, as the regression does not hold there.
+func heatIndex(celsius, humidity float64) float64 {
+	t := celsius*9/5 + 32
+	index := 0.5 * (t + 61 + (t-68)*1.2 + humidity*0.094)
+	if (index+t)/2 >= 80 {
+		index = -42.379 + 2.04901523*t + 10.14333127*humidity -
+			0.22475541*t*humidity - 0.00683783*t*t - 0.05481717*humidity*humidity +
+			0.00122874*t*t*humidity + 0.00085282*t*humidity*humidity -
+			0.00000199*t*t*humidity*humidity
+		switch {
+		case humidity < 13 && t >= 80 && t <= 112:
+			index -= (13 - humidity) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+		case humidity > 85 && t >= 80 && t <= 87:
+			index += (humidity - 85) / 10 * (87 - t) / 5
+		}
+	}
+	return (index - 32) * 5 / 9
+}
+
+// absoluteHumidity returns the mass of water vapour per volume of air in
+// g/m³, based on the saturation vapour pressure after Magnus.
+func absoluteHumidity(celsius, humidity float64) float64 {
+	saturation := 6.112 * math.Exp(17.67*celsius/(celsius+243.5))
+	return saturation * humidity * 2.1674 / (273.15 + celsius)
+}
@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// dnsCache caches the addresses of target host names for DNSCacheTTL, so
+// that frequent scrapes do not query the resolver every time.
+type dnsCache struct {
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+type dnsEntry struct {
+	ip      string
+	expires time.Time
+}
+
+var resolved = &dnsCache{entries: make(map[string]dnsEntry)}
+
+// lookup returns the first address of the host, from the cache if it was
+// resolved less than ttl ago. Failed lookups are not cached.
+func (d *dnsCache) lookup(ctx context.Context, host string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	d.mu.Lock()
+	entry, ok := d.entries[host]
+	d.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		dnsLookups.WithLabelValues("hit").Inc()
+		return entry.ip, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		dnsLookups.WithLabelValues("error").Inc()
+		return "", err
+	}
+	dnsLookups.WithLabelValues("miss").Inc()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Drop expired entries of hosts no longer scraped.
+	for name, e := range d.entries {
+		if !now.Before(e.expires) {
+			delete(d.entries, name)
+		}
+	}
+	d.entries[host] = dnsEntry{ip: addrs[0], expires: now.Add(ttl)}
+	return addrs[0], nil
+}
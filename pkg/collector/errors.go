@@ -0,0 +1,79 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Classes of scrape errors. Errors returned by Scrape and Walk match one of
+// them with errors.Is.
+var (
+	// ErrConnect reports that the device could not be reached.
+	ErrConnect = errors.New("connection failed")
+	// ErrTimeout reports that the device did not respond in time.
+	ErrTimeout = errors.New("timed out")
+	// ErrAuth reports that the device rejected the credentials.
+	ErrAuth = errors.New("authentication failed")
+	// ErrParse reports that the device responded with values that could
+	// not be parsed.
+	ErrParse = errors.New("unparsable response")
+)
+
+// scrapeError attaches the class to an error without changing its message.
+type scrapeError struct {
+	class error
+	err   error
+}
+
+func (e scrapeError) Error() string        { return e.err.Error() }
+func (e scrapeError) Unwrap() error        { return e.err }
+func (e scrapeError) Is(target error) bool { return target == e.class }
+
+// classify wraps the error returned by gosnmp with its class. Errors that
+// are neither timeouts nor authentication failures are connection errors.
+// Errors that already carry a class are returned unchanged.
+func classify(err error) error {
+	if errors.As(err, new(scrapeError)) {
+		return err
+	}
+	class := ErrConnect
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout(),
+		strings.Contains(err.Error(), "request timeout"):
+		class = ErrTimeout
+	case errors.Is(err, gosnmp.ErrWrongDigest),
+		errors.Is(err, gosnmp.ErrUnknownUsername),
+		errors.Is(err, gosnmp.ErrDecryption),
+		errors.Is(err, gosnmp.ErrUnknownSecurityLevel):
+		class = ErrAuth
+	}
+	return scrapeError{class: class, err: err}
+}
+
+// Reason returns a short name of the class of the error for use in labels
+// and log fields: "connect", "timeout", "auth", "parse" or "unknown".
+func Reason(err error) string {
+	switch {
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrParse):
+		return "parse"
+	case errors.Is(err, ErrConnect):
+		return "connect"
+	}
+	return "unknown"
+}
+
+// failover reports whether the scrape error is worth retrying on another
+// address of the device.
+func failover(err error) bool {
+	return errors.Is(err, ErrConnect) || errors.Is(err, ErrTimeout)
+}
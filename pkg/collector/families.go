@@ -0,0 +1,119 @@
+package collector
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Family is a metric family with its default help text and variable labels.
+// Descriptors are built via Collector.Desc, which applies the help texts
+// configured in metric_help, so that Describe and Collect always agree.
+type Family struct {
+	Name   string
+	Help   string
+	Labels []string
+}
+
+// families are all families defined via NewFamily by name, including those
+// of the exporter, to validate metric_help.
+var families = map[string]Family{}
+
+// NewFamily defines a metric family. It is meant for package level
+// variables and panics on duplicate names.
+func NewFamily(name, help string, labels ...string) Family {
+	if _, ok := families[name]; ok {
+		panic("duplicate metric family " + name)
+	}
+	family := Family{Name: name, Help: help, Labels: labels}
+	families[name] = family
+	return family
+}
+
+// FamilyLabels returns the sorted names of the variable labels of all
+// families, before the sensor label is renamed.
+func FamilyLabels() []string {
+	var labels []string
+	for _, family := range families {
+		labels = append(labels, family.Labels...)
+	}
+	slices.Sort(labels)
+	return slices.Compact(labels)
+}
+
+// ValidateMetricHelp checks that all families of the help overrides are
+// known.
+func ValidateMetricHelp(help map[string]string) error {
+	for name, text := range help {
+		if _, ok := families[name]; !ok {
+			return fmt.Errorf("unknown metric family %q in metric_help", name)
+		}
+		if text == "" {
+			return fmt.Errorf("empty metric_help of %s", name)
+		}
+	}
+	return nil
+}
+
+// Desc returns the descriptor of the family with the configured help text
+// and sensor label name.
+func (c Collector) Desc(family Family) *prometheus.Desc {
+	help := family.Help
+	if override, ok := c.MetricHelp[family.Name]; ok {
+		help = override
+	}
+	labels := family.Labels
+	if i := slices.Index(labels, "sensor"); i >= 0 && c.SensorLabel() != "sensor" {
+		labels = slices.Clone(labels)
+		labels[i] = c.SensorLabel()
+	}
+	return prometheus.NewDesc(family.Name, help, labels, nil)
+}
+
+// Families of the sensor readings.
+var (
+	upFamily              = NewFamily("up", "WUT sensor status")
+	scrapePartialFamily   = NewFamily("wut_scrape_partial", "Whether the last scrape of the WUT sensor returned only partial results")
+	sensorConnectedFamily = NewFamily("wut_sensor_connected", "Whether a probe is connected to the WUT sensor channel", "room", "sensor")
+	sensorErrorFamily     = NewFamily("wut_sensor_error", "WUT sensor channel without a valid reading", "room", "sensor", "reason")
+	temperatureFamily     = NewFamily("wut_temperature", "Temperature reading from WUT sensor", "room", "sensor")
+	// temperatureUnitFamilies are the unit suffixed names of
+	// temperatureFamily by unit.
+	temperatureUnitFamilies = map[int]Family{
+		UnitCelsius:    NewFamily("wut_temperature_celsius", "Temperature reading from WUT sensor", "room", "sensor"),
+		UnitFahrenheit: NewFamily("wut_temperature_fahrenheit", "Temperature reading from WUT sensor", "room", "sensor"),
+		UnitKelvin:     NewFamily("wut_temperature_kelvin", "Temperature reading from WUT sensor", "room", "sensor"),
+	}
+	humidityFamily        = NewFamily("wut_humidity", "Relative humidity reading from WUT sensor", "room", "sensor")
+	humidityPercentFamily = NewFamily("wut_humidity_percent", "Relative humidity reading from WUT sensor", "room", "sensor")
+	pressureFamily        = NewFamily("wut_pressure", "Air pressure reading from WUT sensor", "room", "sensor")
+	pressurePascalsFamily = NewFamily("wut_pressure_pascals", "Air pressure reading from WUT sensor", "room", "sensor")
+	analogFamily          = NewFamily("wut_analog", "Analog input reading from WUT sensor", "room", "sensor")
+	clockOffsetFamily     = NewFamily("wut_device_clock_offset_seconds", "Offset of the WUT device clock against the exporter clock")
+)
+
+// Families of the profiles.
+var (
+	relayStateFamily       = NewFamily("wut_relay_state", "State of the WUT alarm relay or switching output (1 = active)", "room", "output")
+	diagnosticErrorsFamily = NewFamily("wut_device_diagnostic_errors_total", "Internal error and sensor bus error counters of the WUT device", "room", "counter")
+	diagnosticValuesFamily = NewFamily("wut_device_diagnostic_value", "Numeric values of the WUT device diagnostics that are not counters", "room", "value")
+	deviceInfoFamily       = NewFamily("wut_device_info", "Identity of the WUT device", identityLabels()...)
+	alarmTriggersFamily    = NewFamily("wut_alarm_triggers_total", "Number of times the alarm configured on the WUT device has been triggered", "room", "alarm", "name")
+)
+
+// collectorFamilies are all families collected by a Collector, in the order
+// they are described.
+func collectorFamilies() []Family {
+	result := []Family{upFamily, scrapePartialFamily, sensorConnectedFamily, sensorErrorFamily, temperatureFamily}
+	for _, unit := range []int{UnitCelsius, UnitFahrenheit, UnitKelvin} {
+		result = append(result, temperatureUnitFamilies[unit])
+	}
+	result = append(result, humidityFamily, humidityPercentFamily, pressureFamily, pressurePascalsFamily, analogFamily,
+		heatIndexFamily, heatIndexCelsiusFamily, absoluteHumidityFamily, absoluteHumidityGramsFamily, clockOffsetFamily,
+		relayStateFamily, diagnosticErrorsFamily, diagnosticValuesFamily, deviceInfoFamily, alarmTriggersFamily)
+	for _, counter := range interfaceCounters {
+		result = append(result, counter.family)
+	}
+	return result
+}
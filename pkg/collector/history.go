@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Sample is a value of a reading in one of its metric families, for
+// sending readings to Prometheus without a scrape, like the backfill of
+// the data logger history.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Samples returns the reading in the metric families and with the labels
+// of a scrape.
+func (c Collector) Samples(reading Reading) []Sample {
+	var result []Sample
+	for _, v := range c.readingValues(reading) {
+		result = append(result, Sample{
+			Name:   v.family.Name,
+			Labels: map[string]string{"room": c.RoomLabel(), c.SensorLabel(): reading.Sensor},
+			Value:  v.value,
+		})
+	}
+	return result
+}
+
+// HistoryUnits returns the unit the device records temperatures in and the
+// unit they are exported in, querying the device via SNMP like a scrape if
+// the options depend on it.
+func (c Collector) HistoryUnits(ctx context.Context) (deviceUnit, unit int, err error) {
+	if !c.needsUnit() {
+		return UnitCelsius, UnitCelsius, nil
+	}
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, c.Ip)
+	}
+	if err := snmp.Connect(); err != nil {
+		return 0, 0, fmt.Errorf("connecting to SNMP target: %w", err)
+	}
+	defer snmp.Close()
+	deviceUnit, unit = c.units(snmp)
+	return deviceUnit, unit, nil
+}
+
+// HistoryReading converts a temperature recorded by the data logger of the
+// device in the units returned by HistoryUnits to a reading like a scrape.
+// It reports false for readings outside of the bounds.
+func (c Collector) HistoryReading(sensor string, value float64, deviceUnit, unit int, timestamp time.Time) (Reading, bool) {
+	if c.NormalizeUnit {
+		value = toCelsius(value, deviceUnit)
+	}
+	if !c.Bounds.Contains(value) {
+		return Reading{}, false
+	}
+	return Reading{Sensor: sensor, Value: value, Unit: unit, Timestamp: timestamp}, true
+}
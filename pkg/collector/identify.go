@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// wutEnterpriseOID is the enterprise number of Wiesemann & Theis, the
+// prefix of the sysObjectID of their devices.
+const wutEnterpriseOID = "1.3.6.1.4.1.5040."
+
+// Identity is the system group of an SNMP agent.
+type Identity struct {
+	ObjectID    string
+	Description string
+	Name        string
+	Location    string
+}
+
+// IsWUT reports whether the agent is a W&T device.
+func (i Identity) IsWUT() bool {
+	return strings.HasPrefix(strings.TrimPrefix(i.ObjectID, "."), wutEnterpriseOID)
+}
+
+// Identify queries the system group of the device, e.g. to tell W&T devices
+// apart from other agents during discovery.
+func (c Collector) Identify(ctx context.Context) (Identity, error) {
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, c.Ip)
+	}
+	if err := snmp.Connect(); err != nil {
+		return Identity{}, fmt.Errorf("connecting to SNMP target: %w", scrapeError{class: ErrConnect, err: err})
+	}
+	defer snmp.Close()
+
+	var identity Identity
+	for i, scalar := range []struct {
+		oid   string
+		value *string
+	}{
+		{"1.3.6.1.2.1.1.2.0", &identity.ObjectID},
+		{"1.3.6.1.2.1.1.1.0", &identity.Description},
+		{"1.3.6.1.2.1.1.5.0", &identity.Name},
+		{"1.3.6.1.2.1.1.6.0", &identity.Location},
+	} {
+		// SNMPv1 fails the whole request if any OID is unknown, so every
+		// scalar is queried on its own.
+		packet, err := snmp.Get([]string{scalar.oid})
+		if err != nil {
+			if i == 0 {
+				// Not an agent answering with the community.
+				return Identity{}, classify(err)
+			}
+			continue
+		}
+		if len(packet.Variables) == 1 {
+			*scalar.value = strings.TrimSpace(PDUString(packet.Variables[0]))
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,72 @@
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Registry holds the self-monitoring metrics of all scrapes, such as SNMP
+// packet counters and parse failures. It is served on /metrics by the
+// exporter alongside its own metrics.
+var Registry = prometheus.NewRegistry()
+
+var scrapesInFlight = promauto.With(Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "wut_scrapes_in_flight",
+	Help: "Number of target scrapes currently in progress.",
+})
+
+var parseFailures = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_parse_failures_total",
+	Help: "Total number of sensor values that could not be parsed as a number.",
+}, []string{"target", "sensor"})
+
+var snmpPacketsSent = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_snmp_requests_sent_total",
+	Help: "Total number of SNMP request packets sent to the target.",
+}, []string{"target"})
+
+var snmpPacketsReceived = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_snmp_responses_received_total",
+	Help: "Total number of SNMP response packets received from the target.",
+}, []string{"target"})
+
+var snmpTimeouts = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_snmp_timeouts_total",
+	Help: "Total number of SNMP requests to the target that were retried because no valid response arrived in time.",
+}, []string{"target"})
+
+var scrapeDuration = promauto.With(Registry).NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "wut_scrape_duration_seconds",
+	Help:    "Duration of the scrapes of the target including retries and failovers.",
+	Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+}, []string{"target"})
+
+var scrapeErrors = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_scrape_errors_total",
+	Help: "Total number of failed scrapes of the target by reason: connect, timeout, auth or parse.",
+}, []string{"target", "reason"})
+
+var lastScrapeSuccess = promauto.With(Registry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_last_scrape_success_timestamp_seconds",
+	Help: "Unix timestamp of the last successful scrape of the target.",
+}, []string{"target"})
+
+var outOfRange = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_out_of_range_total",
+	Help: "Total number of sensor readings dropped for lying outside of the configured bounds.",
+}, []string{"target", "sensor"})
+
+var varbindLimitExceeded = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_varbind_limit_exceeded_total",
+	Help: "Total number of scrapes of the target truncated for returning more than max_varbinds varbinds.",
+}, []string{"target"})
+
+var addressFailovers = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_address_failovers_total",
+	Help: "Total number of scrapes of the target retried on its next address after the previous one failed.",
+}, []string{"target"})
+
+var dnsLookups = promauto.With(Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "wut_dns_lookups_total",
+	Help: "Total number of target host name lookups by result: hit, miss or error.",
+}, []string{"result"})
@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// MockClient is an in-memory SNMPClient serving a fixed set of varbinds.
+// Walks return all PDUs below the root OID in the order they were added.
+type MockClient struct {
+	// PDUs are the varbinds of the simulated device.
+	PDUs []gosnmp.SnmpPDU
+	// ConnectErr, if set, is returned by Connect.
+	ConnectErr error
+	// WalkErrs maps root OIDs to errors returned by walks of them after
+	// all matching PDUs, simulating walks failing mid-way.
+	WalkErrs map[string]error
+}
+
+// Connect implements SNMPClient.
+func (m *MockClient) Connect() error {
+	return m.ConnectErr
+}
+
+// Close implements SNMPClient.
+func (m *MockClient) Close() error {
+	return nil
+}
+
+// Get implements SNMPClient. Like SNMPv1 devices it fails the whole
+// request if any of the OIDs is unknown.
+func (m *MockClient) Get(oids []string) (*gosnmp.SnmpPacket, error) {
+	packet := &gosnmp.SnmpPacket{}
+	for _, oid := range oids {
+		pdu, ok := m.find(oid)
+		if !ok {
+			return nil, fmt.Errorf("no such name %s", oid)
+		}
+		packet.Variables = append(packet.Variables, pdu)
+	}
+	return packet, nil
+}
+
+// find returns the PDU of the OID.
+func (m *MockClient) find(oid string) (gosnmp.SnmpPDU, bool) {
+	for _, pdu := range m.PDUs {
+		if normalizeOID(pdu.Name) == normalizeOID(oid) {
+			return pdu, true
+		}
+	}
+	return gosnmp.SnmpPDU{}, false
+}
+
+// Walk implements SNMPClient.
+func (m *MockClient) Walk(rootOid string, walkFn gosnmp.WalkFunc) error {
+	root := normalizeOID(rootOid)
+	for _, pdu := range m.PDUs {
+		if strings.HasPrefix(normalizeOID(pdu.Name), root+".") {
+			if err := walkFn(pdu); err != nil {
+				return err
+			}
+		}
+	}
+	return m.WalkErrs[rootOid]
+}
+
+// WalkAll returns all PDUs below the root OID.
+func (m *MockClient) WalkAll(rootOid string) ([]gosnmp.SnmpPDU, error) {
+	return walkAll(m, rootOid)
+}
+
+// normalizeOID strips the leading dot gosnmp adds to returned OIDs.
+func normalizeOID(oid string) string {
+	return strings.TrimPrefix(oid, ".")
+}
@@ -0,0 +1,289 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// profile is an optional set of additional OIDs walked on every scrape,
+// enabled per target via the profiles option.
+type profile func(c Collector, snmp SNMPClient) ([]prometheus.Metric, error)
+
+// profiles maps the names usable in the configuration to their profile.
+var profiles = map[string]profile{
+	"relays":      relayProfile,
+	"diagnostics": diagnosticsProfile,
+	"interfaces":  interfacesProfile,
+	"identity":    identityProfile,
+	"alarms":      alarmsProfile,
+}
+
+// collectProfiles walks all enabled profiles. Failing profiles are logged
+// and skipped so that they never affect the sensor readings.
+func (c Collector) collectProfiles(snmp SNMPClient) []prometheus.Metric {
+	var result []prometheus.Metric
+	for _, name := range c.Profiles {
+		metrics, err := profiles[name](c, snmp)
+		if err != nil {
+			c.Logger.Warn("Error collecting profile", zap.String("ip", c.Ip), zap.String("profile", name), zap.Error(err))
+			continue
+		}
+		result = append(result, metrics...)
+	}
+	return result
+}
+
+// ValidateProfiles checks that all configured profile names are known.
+func ValidateProfiles(names []string) error {
+	for _, name := range names {
+		if _, ok := profiles[name]; !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	return nil
+}
+
+// PDUString returns the textual value of an OCTET STRING or INTEGER PDU.
+func PDUString(pdu gosnmp.SnmpPDU) string {
+	switch value := pdu.Value.(type) {
+	case string:
+		return value
+	case []uint8:
+		return string(value)
+	case int:
+		return strconv.Itoa(value)
+	}
+	return ""
+}
+
+// relayStateOID is the table of the alarm relay and switching output
+// states, indexed by output number.
+const relayStateOID = "1.3.6.1.4.1.5040.1.2.6.1.5.1.1"
+
+// relayProfile exports the state of the alarm relay and switching outputs.
+func relayProfile(c Collector, snmp SNMPClient) ([]prometheus.Metric, error) {
+	states, err := walkAll(snmp, relayStateOID)
+	if err != nil {
+		return nil, fmt.Errorf("walking relay states: %w", err)
+	}
+
+	desc := c.Desc(relayStateFamily)
+	var result []prometheus.Metric
+	for _, pdu := range states {
+		value := 0.0
+		switch strings.ToLower(strings.TrimSpace(PDUString(pdu))) {
+		case "1", "on", "ein", "active":
+			value = 1
+		}
+		result = append(result, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue,
+			value,
+			c.RoomLabel(), strconv.Itoa(OIDIndex(pdu.Name)),
+		))
+	}
+	return result, nil
+}
+
+// diagnosticsOID is the diagnostic branch holding the internal error and
+// sensor bus error counters of the device.
+const diagnosticsOID = "1.3.6.1.4.1.5040.1.2.6.2"
+
+// diagnosticsProfile exports the counters of the diagnostic branch, labeled
+// by their OID relative to the branch. Other numeric values may go down and
+// are exported as gauges.
+func diagnosticsProfile(c Collector, snmp SNMPClient) ([]prometheus.Metric, error) {
+	counters, err := walkAll(snmp, diagnosticsOID)
+	if err != nil {
+		return nil, fmt.Errorf("walking diagnostic counters: %w", err)
+	}
+
+	counterDesc, gaugeDesc := c.Desc(diagnosticErrorsFamily), c.Desc(diagnosticValuesFamily)
+	var result []prometheus.Metric
+	for _, pdu := range counters {
+		var desc *prometheus.Desc
+		var valueType prometheus.ValueType
+		switch pdu.Type {
+		case gosnmp.Counter32, gosnmp.Counter64:
+			desc, valueType = counterDesc, prometheus.CounterValue
+		case gosnmp.Integer, gosnmp.Gauge32, gosnmp.Uinteger32:
+			desc, valueType = gaugeDesc, prometheus.GaugeValue
+		default:
+			continue
+		}
+		value, _ := gosnmp.ToBigInt(pdu.Value).Float64()
+		counter := strings.TrimPrefix(strings.TrimPrefix(pdu.Name, "."), diagnosticsOID+".")
+		result = append(result, prometheus.MustNewConstMetric(desc, valueType,
+			value,
+			c.RoomLabel(), counter,
+		))
+	}
+	return result, nil
+}
+
+// interfaceCounters maps the ifTable columns exported by the interfaces
+// profile to their metric families.
+var interfaceCounters = []struct {
+	oid    string
+	family Family
+}{
+	{"1.3.6.1.2.1.2.2.1.10", NewFamily("wut_interface_receive_bytes_total", "Octets received on the network interface of the WUT device", "room", "interface")},
+	{"1.3.6.1.2.1.2.2.1.14", NewFamily("wut_interface_receive_errors_total", "Inbound packets with errors on the network interface of the WUT device", "room", "interface")},
+	{"1.3.6.1.2.1.2.2.1.16", NewFamily("wut_interface_transmit_bytes_total", "Octets transmitted on the network interface of the WUT device", "room", "interface")},
+	{"1.3.6.1.2.1.2.2.1.20", NewFamily("wut_interface_transmit_errors_total", "Outbound packets with errors on the network interface of the WUT device", "room", "interface")},
+}
+
+// ifDescrOID is the interface name column of the ifTable.
+const ifDescrOID = "1.3.6.1.2.1.2.2.1.2"
+
+// interfacesProfile exports traffic and error counters of the network
+// interfaces from the standard ifTable.
+func interfacesProfile(c Collector, snmp SNMPClient) ([]prometheus.Metric, error) {
+	descriptions, err := walkAll(snmp, ifDescrOID)
+	if err != nil {
+		return nil, fmt.Errorf("walking interface names: %w", err)
+	}
+	names := make(map[int]string, len(descriptions))
+	for _, pdu := range descriptions {
+		names[OIDIndex(pdu.Name)] = PDUString(pdu)
+	}
+
+	var result []prometheus.Metric
+	for _, counter := range interfaceCounters {
+		values, err := walkAll(snmp, counter.oid)
+		if err != nil {
+			return nil, fmt.Errorf("walking %s: %w", counter.oid, err)
+		}
+		desc := c.Desc(counter.family)
+		for _, pdu := range values {
+			value, _ := gosnmp.ToBigInt(pdu.Value).Float64()
+			name, ok := names[OIDIndex(pdu.Name)]
+			if !ok {
+				name = strconv.Itoa(OIDIndex(pdu.Name))
+			}
+			result = append(result, prometheus.MustNewConstMetric(desc, prometheus.CounterValue,
+				value,
+				c.RoomLabel(), name,
+			))
+		}
+	}
+	return result, nil
+}
+
+// firmwareOID is the firmware version of the device.
+const firmwareOID = "1.3.6.1.4.1.5040.1.2.6.3.1.4.0"
+
+// Firmware queries the firmware version of the device.
+func (c Collector) Firmware(ctx context.Context) (string, error) {
+	if c.Simulation != nil {
+		return "simulated", nil
+	}
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, c.Ip)
+	}
+	if err := snmp.Connect(); err != nil {
+		return "", fmt.Errorf("connecting to SNMP target: %w", scrapeError{class: ErrConnect, err: err})
+	}
+	defer snmp.Close()
+
+	packet, err := snmp.Get([]string{firmwareOID})
+	if err != nil {
+		return "", fmt.Errorf("querying firmware: %w", classify(err))
+	}
+	if len(packet.Variables) != 1 {
+		return "", errors.New("querying firmware: no value returned")
+	}
+	return strings.TrimSpace(PDUString(packet.Variables[0])), nil
+}
+
+// identityOIDs are the scalars exported as labels of wut_device_info.
+var identityOIDs = []struct {
+	label string
+	oid   string
+}{
+	{"description", "1.3.6.1.2.1.1.1.0"},
+	{"firmware", firmwareOID},
+	{"article", "1.3.6.1.4.1.5040.1.2.6.3.1.3.0"},
+	// ifPhysAddress of the first interface.
+	{"mac", "1.3.6.1.2.1.2.2.1.6.1"},
+}
+
+// identityLabels are the labels of wut_device_info.
+func identityLabels() []string {
+	labels := []string{"room"}
+	for _, identity := range identityOIDs {
+		labels = append(labels, identity.label)
+	}
+	return labels
+}
+
+// identityProfile exports the device description, firmware version, article
+// number and MAC address as wut_device_info. Values the device does not
+// provide are left empty.
+func identityProfile(c Collector, snmp SNMPClient) ([]prometheus.Metric, error) {
+	values := []string{c.RoomLabel()}
+	for _, identity := range identityOIDs {
+		value := ""
+		// SNMPv1 fails the whole request if any OID is unknown, so every
+		// scalar is queried on its own.
+		packet, err := snmp.Get([]string{identity.oid})
+		if err == nil && len(packet.Variables) == 1 {
+			pdu := packet.Variables[0]
+			if identity.label == "mac" {
+				if b, ok := pdu.Value.([]uint8); ok {
+					value = net.HardwareAddr(b).String()
+				}
+			} else {
+				value = strings.TrimSpace(PDUString(pdu))
+			}
+		}
+		values = append(values, value)
+	}
+
+	return []prometheus.Metric{prometheus.MustNewConstMetric(c.Desc(deviceInfoFamily), prometheus.GaugeValue,
+		1,
+		values...,
+	)}, nil
+}
+
+// Alarm tables of the device, indexed by alarm number.
+const (
+	alarmTriggerCountOID = "1.3.6.1.4.1.5040.1.2.6.1.6.1.1"
+	alarmNameOID         = "1.3.6.1.4.1.5040.1.2.6.3.3.1.1.1"
+)
+
+// alarmsProfile exports how often each configured device-side alarm has
+// been triggered, labeled by the alarm name configured on the device.
+func alarmsProfile(c Collector, snmp SNMPClient) ([]prometheus.Metric, error) {
+	counts, err := walkAll(snmp, alarmTriggerCountOID)
+	if err != nil {
+		return nil, fmt.Errorf("walking alarm trigger counters: %w", err)
+	}
+	alarmNames, err := walkAll(snmp, alarmNameOID)
+	if err != nil {
+		return nil, fmt.Errorf("walking alarm names: %w", err)
+	}
+	names := make(map[int]string, len(alarmNames))
+	for _, pdu := range alarmNames {
+		names[OIDIndex(pdu.Name)] = strings.TrimSpace(PDUString(pdu))
+	}
+
+	desc := c.Desc(alarmTriggersFamily)
+	var result []prometheus.Metric
+	for _, pdu := range counts {
+		value, _ := gosnmp.ToBigInt(pdu.Value).Float64()
+		index := OIDIndex(pdu.Name)
+		result = append(result, prometheus.MustNewConstMetric(desc, prometheus.CounterValue,
+			value,
+			c.RoomLabel(), strconv.Itoa(index), names[index],
+		))
+	}
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package collector
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// simulate returns synthetic readings for the given point in time. Every
+// sensor is slightly offset so they can be told apart.
+func (c Collector) simulate(now time.Time) ([]prometheus.Metric, []Reading) {
+	s := c.Simulation
+	phase := 2 * math.Pi * float64(now.UnixNano()%s.Period.Nanoseconds()) / float64(s.Period.Nanoseconds())
+
+	result := make([]prometheus.Metric, 0, len(s.Sensors))
+	readings := make([]Reading, 0, len(s.Sensors))
+	for i, sensor := range s.Sensors {
+		offset := float64(i) * 0.5
+		noise := (rand.Float64()*2 - 1) * *s.Noise
+		value := *s.Base + offset + *s.Amplitude*math.Sin(phase+float64(i)) + noise
+		// Devices report with a single decimal.
+		result = append(result, c.connected(sensor, true))
+		readings = append(readings, Reading{Sensor: sensor, Value: math.Round(value*10) / 10, Unit: UnitCelsius, Timestamp: now})
+	}
+	return result, readings
+}
+
+// SimulatedMetrics returns the metrics of a simulated scrape without querying the
+// device or updating the self-monitoring metrics, e.g. to discover the
+// metric families exported with the options of the collector.
+func (c Collector) SimulatedMetrics(now time.Time) []prometheus.Metric {
+	if c.Simulation == nil {
+		simulation := config.Simulation{}.WithDefaults()
+		c.Simulation = &simulation
+	}
+	metrics, readings := c.simulate(now)
+	return c.Metrics(Result{Metrics: metrics, Readings: readings, Timestamp: now})
+}
@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of the scrapes with the global tracer provider,
+// which discards them unless the application installs an exporter.
+var tracer = otel.Tracer("github.com/hm-edu/wut-temperature-exporter/pkg/collector")
+
+// exemplar returns the exemplar labels linking a sample to the trace of the
+// context, or nil if the context carries no sampled span.
+func exemplar(ctx context.Context) prometheus.Labels {
+	span := trace.SpanContextFromContext(ctx)
+	if !span.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": span.TraceID().String()}
+}
+
+// observe records the value with the exemplar of the context, if any.
+func observe(ctx context.Context, observer prometheus.Observer, value float64) {
+	if labels := exemplar(ctx); labels != nil {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(value, labels)
+		return
+	}
+	observer.Observe(value)
+}
+
+// inc increments the counter with the exemplar of the context, if any.
+func inc(ctx context.Context, counter prometheus.Counter) {
+	if labels := exemplar(ctx); labels != nil {
+		counter.(prometheus.ExemplarAdder).AddWithExemplar(1, labels)
+		return
+	}
+	counter.Inc()
+}
@@ -0,0 +1,141 @@
+package collector
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// unitOID is the temperature unit configured on the device.
+const unitOID = "1.3.6.1.4.1.5040.1.2.6.3.1.5.1.0"
+
+// Temperature units as reported by unitOID, used in Reading.Unit.
+const (
+	UnitCelsius    = 0
+	UnitFahrenheit = 1
+	UnitKelvin     = 2
+)
+
+// Units of the channels that do not measure temperature, used in
+// Reading.Unit.
+const (
+	UnitPercentRH   = 10
+	UnitHectopascal = 11
+	// UnitAnalog is the scale of an analog input configured on the device.
+	UnitAnalog = 12
+)
+
+// IsTemperature reports whether readings in the unit are temperatures.
+func IsTemperature(unit int) bool {
+	return unit != UnitPercentRH && unit != UnitHectopascal && unit != UnitAnalog
+}
+
+// channelLayouts are the units of the channels of the device types with a
+// fixed assignment of measurements to channels. Temperature channels are
+// listed as UnitCelsius and report the unit of the device.
+var channelLayouts = map[string][]int{
+	config.DeviceTypeThermoHygrometer:     {UnitCelsius, UnitPercentRH},
+	config.DeviceTypeThermoHygrobarometer: {UnitCelsius, UnitPercentRH, UnitHectopascal},
+}
+
+// channelUnit returns the unit of the raw value of the channel at the OID
+// index according to the device type, or the temperature unit if the
+// channel measures temperature.
+func (c Collector) channelUnit(index int, raw string, unit int) int {
+	switch c.DeviceType {
+	case config.DeviceTypeThermometer:
+		return unit
+	case config.DeviceTypeAnalog:
+		return UnitAnalog
+	case config.DeviceTypeAuto, "":
+		return sensorUnit(raw, unit)
+	}
+	if layout := channelLayouts[c.DeviceType]; index >= 1 && index <= len(layout) && !IsTemperature(layout[index-1]) {
+		return layout[index-1]
+	}
+	return unit
+}
+
+// sensorUnit detects the unit of a raw sensor value of a channel measuring
+// humidity or air pressure, and returns the temperature unit for all other
+// values. The sensor table of the devices holds all channels regardless of
+// their type, which leaves the unit formatted into the string values by the
+// firmware to tell them apart.
+func sensorUnit(raw string, unit int) int {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	switch {
+	case strings.HasSuffix(value, "%") || strings.HasSuffix(value, "%rh") || strings.HasSuffix(value, "% rh"):
+		return UnitPercentRH
+	case strings.HasSuffix(value, "hpa") || strings.HasSuffix(value, "mbar"):
+		return UnitHectopascal
+	}
+	return unit
+}
+
+// unit queries the temperature unit configured on the device. Celsius is
+// assumed if the device does not report a unit.
+func (c Collector) unit(snmp SNMPClient) int {
+	packet, err := snmp.Get([]string{unitOID})
+	if err != nil || len(packet.Variables) != 1 {
+		c.Logger.Debug("Error reading device unit, assuming Celsius", zap.String("ip", c.Ip), zap.Error(err))
+		return UnitCelsius
+	}
+
+	switch value := packet.Variables[0].Value.(type) {
+	case int:
+		return value
+	case []uint8:
+		return ParseUnit(string(value))
+	case string:
+		return ParseUnit(value)
+	}
+	return UnitCelsius
+}
+
+// needsUnit reports whether the readings depend on the unit configured on
+// the device.
+func (c Collector) needsUnit() bool {
+	// The derived metrics need the unit even if the readings are exported
+	// as reported.
+	return c.NormalizeUnit || c.MetricNames != config.MetricNamesLegacy || c.DerivedMetrics
+}
+
+// units returns the unit the device reports temperatures in and the unit
+// they are exported in. The device is only queried if the options depend
+// on it, Celsius is assumed otherwise.
+func (c Collector) units(snmp SNMPClient) (deviceUnit, unit int) {
+	if !c.needsUnit() {
+		return UnitCelsius, UnitCelsius
+	}
+	deviceUnit = c.unit(snmp)
+	if c.NormalizeUnit {
+		return deviceUnit, UnitCelsius
+	}
+	return deviceUnit, deviceUnit
+}
+
+// ParseUnit maps a textual unit such as "°F" to the unit constants,
+// defaulting to Celsius.
+func ParseUnit(unit string) int {
+	unit = strings.ToUpper(strings.TrimSpace(unit))
+	switch {
+	case strings.HasSuffix(unit, "F"):
+		return UnitFahrenheit
+	case strings.HasSuffix(unit, "K"):
+		return UnitKelvin
+	}
+	return UnitCelsius
+}
+
+// toCelsius converts a reading in the given unit to degrees Celsius.
+func toCelsius(value float64, unit int) float64 {
+	switch unit {
+	case UnitFahrenheit:
+		return (value - 32) * 5 / 9
+	case UnitKelvin:
+		return value - 273.15
+	}
+	return value
+}
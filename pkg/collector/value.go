@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// numberPattern matches a plain decimal number once separators have been
+// normalized. It rejects the hex, exponent, NaN and Inf syntax accepted by
+// strconv.ParseFloat, which never occurs in device output.
+var numberPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)$`)
+
+var errNotANumber = errors.New("not a decimal number")
+
+// placeholders are the values other firmware languages report for a
+// channel without a probe, compared case-insensitively.
+var placeholders = []string{"", "n/a", "n.a.", "k.a.", "n.v.", "n.c.", "nc"}
+
+// IsPlaceholder reports whether a raw sensor value is the placeholder the
+// firmware reports for a channel without a probe rather than a reading.
+// Besides the "--" of the English and German firmware, the variants of
+// other firmware languages and the configured tokens are placeholders.
+// Other values that are no number are parse failures.
+func IsPlaceholder(raw string, tokens []string) bool {
+	if strings.Contains(raw, "--") {
+		return true
+	}
+	lower := strings.ToLower(raw)
+	if slices.Contains(placeholders, strings.TrimSpace(lower)) {
+		return true
+	}
+	for _, token := range tokens {
+		if token != "" && strings.Contains(lower, strings.ToLower(token)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseValue converts a sensor value as formatted by the device firmware to
+// a number. Depending on the locale configured on the device values use a
+// decimal comma or point, may contain thousands separators and are
+// sometimes followed by the unit, e.g. "1.234,5 °C".
+func ParseValue(raw string) (float64, error) {
+	value := strings.TrimRightFunc(strings.TrimSpace(raw), func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsSpace(r) || r == '°' || r == '%'
+	})
+	value = normalizeSeparators(value)
+	if !numberPattern.MatchString(value) {
+		return 0, fmt.Errorf("parsing %q: %w", raw, errNotANumber)
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", raw, err)
+	}
+	if math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, fmt.Errorf("parsing %q: %w", raw, errNotANumber)
+	}
+	return number, nil
+}
+
+// Numbers with thousands separators grouping the integer digits.
+var (
+	pointGrouped = regexp.MustCompile(`^[+-]?\d{1,3}(\.\d{3})+(,\d*)?$`)
+	commaGrouped = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?$`)
+)
+
+// normalizeSeparators rewrites the value to use a decimal point without
+// thousands separators. A separator is only taken as thousands separator
+// if it groups the digits in threes and either occurs more than once or is
+// followed by the other separator. A lone separator is always the decimal
+// separator, as the devices report fractional degrees.
+func normalizeSeparators(value string) string {
+	points, commas := strings.Count(value, "."), strings.Count(value, ",")
+	both := points > 0 && commas > 0
+	switch {
+	case (both || points > 1) && pointGrouped.MatchString(value):
+		return strings.Replace(strings.ReplaceAll(value, ".", ""), ",", ".", 1)
+	case (both || commas > 1) && commaGrouped.MatchString(value):
+		return strings.ReplaceAll(value, ",", "")
+	case commas == 1 && points == 0:
+		return strings.Replace(value, ",", ".", 1)
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		raw   string
+		value float64
+		ok    bool
+	}{
+		{"21,5", 21.5, true},
+		{"21.5", 21.5, true},
+		{" -3,25 ", -3.25, true},
+		{"+7", 7, true},
+		{",5", 0.5, true},
+		{"21,5 °C", 21.5, true},
+		{"70.7°F", 70.7, true},
+		{"294.15 K", 294.15, true},
+		{"45 %", 45, true},
+		{"1.234,5", 1234.5, true},
+		{"1,234.5", 1234.5, true},
+		{"1,234,567", 1234567, true},
+		{"1.234.567", 1234567, true},
+		{"", 0, false},
+		{"°C", 0, false},
+		{"NaN", 0, false},
+		{"Inf", 0, false},
+		{"-Infinity", 0, false},
+		{"1e3", 0, false},
+		{"0x1p4", 0, false},
+		{"1_000", 0, false},
+		{"21,5,3.1", 0, false},
+		{"--", 0, false},
+		{"12 34", 0, false},
+	}
+	for _, tt := range tests {
+		value, err := ParseValue(tt.raw)
+		if (err == nil) != tt.ok || value != tt.value {
+			t.Errorf("ParseValue(%q) = %v, %v, want %v, ok %v", tt.raw, value, err, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestIsPlaceholder(t *testing.T) {
+	tokens := []string{"Err 1"}
+	tests := []struct {
+		raw         string
+		placeholder bool
+	}{
+		{"--", true},
+		{"---.-", true},
+		{"k.A.", true},
+		{" NC ", true},
+		{"", true},
+		{"err 1", true},
+		{"N/A", true},
+		{"ERR", false},
+		{"°C", false},
+		{"21,5", false},
+		{"-3.25 °C", false},
+		{"12 34", false},
+	}
+	for _, tt := range tests {
+		if placeholder := IsPlaceholder(tt.raw, tokens); placeholder != tt.placeholder {
+			t.Errorf("IsPlaceholder(%q) = %v, want %v", tt.raw, placeholder, tt.placeholder)
+		}
+	}
+}
+
+func FuzzParseValue(f *testing.F) {
+	for _, seed := range []string{"21,5", "-3.25", "1.234,5", "1,234.5", "21,5 °C", "----", "NaN", "1e308", "99999999999999999999999999999999999999"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		value, err := ParseValue(raw)
+		if err != nil {
+			return
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			t.Fatalf("ParseValue(%q) returned non-finite %v", raw, value)
+		}
+		// Accepted values survive a round trip through the plain format.
+		formatted := strconv.FormatFloat(value, 'f', -1, 64)
+		again, err := ParseValue(formatted)
+		if err != nil || again != value {
+			t.Fatalf("ParseValue(%q) = %v does not round trip via %q: %v, %v", raw, value, formatted, again, err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package collector
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// WalkRoot is the OID of the W&T enterprise subtree walked by Walk by
+// default.
+const WalkRoot = "1.3.6.1.4.1.5040"
+
+// Varbind is a raw varbind returned by the device.
+type Varbind struct {
+	OID   string `json:"oid"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	// Hex holds the raw bytes of OCTET STRING values, which may not be
+	// valid UTF-8.
+	Hex string `json:"hex,omitempty"`
+}
+
+// Walk walks the subtree below the OID and returns the varbinds as
+// received, for diagnosing devices returning unexpected data. Varbinds
+// received before an error are returned alongside it.
+func (c Collector) Walk(ctx context.Context, oid string) ([]Varbind, error) {
+	if c.Simulation != nil {
+		return nil, errors.New("simulated targets cannot be walked")
+	}
+	snmp := c.Client
+	if snmp == nil {
+		snmp = c.newClient(ctx, c.Ip)
+	}
+	if err := snmp.Connect(); err != nil {
+		return nil, fmt.Errorf("connecting to SNMP target: %w", scrapeError{class: ErrConnect, err: err})
+	}
+	defer snmp.Close()
+
+	var varbinds []Varbind
+	err := c.limit(snmp).Walk(oid, func(pdu gosnmp.SnmpPDU) error {
+		varbinds = append(varbinds, newVarbind(pdu))
+		return nil
+	})
+	if err != nil {
+		return varbinds, fmt.Errorf("walking %s: %w", oid, classify(err))
+	}
+	return varbinds, nil
+}
+
+// limitedClient fails walks once more than max varbinds were received in
+// total, protecting the exporter from devices returning runaway subtrees.
+// The varbinds received until then are still processed, so the scrape is
+// truncated to a partial result.
+type limitedClient struct {
+	SNMPClient
+	target   string
+	max      int
+	received *int
+}
+
+// limit returns the client enforcing MaxVarbinds on all walks of the
+// client, or the client itself if there is no limit.
+func (c Collector) limit(snmp SNMPClient) SNMPClient {
+	if c.MaxVarbinds <= 0 {
+		return snmp
+	}
+	return limitedClient{SNMPClient: snmp, target: c.target(), max: c.MaxVarbinds, received: new(int)}
+}
+
+// Walk implements SNMPClient.
+func (l limitedClient) Walk(rootOid string, walkFn gosnmp.WalkFunc) error {
+	return l.SNMPClient.Walk(rootOid, func(pdu gosnmp.SnmpPDU) error {
+		*l.received++
+		if *l.received > l.max {
+			varbindLimitExceeded.WithLabelValues(l.target).Inc()
+			return scrapeError{class: ErrParse, err: fmt.Errorf("device returned more than %d varbinds", l.max)}
+		}
+		return walkFn(pdu)
+	})
+}
+
+// walkAll returns all varbinds below the root OID. It is used for tables
+// that are looked up by index, unlike the sensor values which are processed
+// as they are received. Varbinds received before an error are returned
+// alongside it.
+func walkAll(snmp SNMPClient, rootOid string) ([]gosnmp.SnmpPDU, error) {
+	var pdus []gosnmp.SnmpPDU
+	err := snmp.Walk(rootOid, func(pdu gosnmp.SnmpPDU) error {
+		pdus = append(pdus, pdu)
+		return nil
+	})
+	return pdus, err
+}
+
+func newVarbind(pdu gosnmp.SnmpPDU) Varbind {
+	v := Varbind{OID: pdu.Name, Type: pdu.Type.String()}
+	switch value := pdu.Value.(type) {
+	case []uint8:
+		v.Hex = hex.EncodeToString(value)
+		if utf8.Valid(value) {
+			v.Value = string(value)
+		}
+	case nil:
+	default:
+		v.Value = fmt.Sprint(value)
+	}
+	return v
+}
@@ -0,0 +1,362 @@
+// Package config holds the configuration types shared by the exporter and
+// the collector: the scraped targets and the options controlling how their
+// readings are collected and exported. The types carry mapstructure tags so
+// they can be decoded with viper or mapstructure directly.
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Target is a single WUT device.
+type Target struct {
+	// IP is the address or host name of the device.
+	IP string `mapstructure:"ip"`
+	// Room is the room label of the exported metrics.
+	Room string `mapstructure:"room"`
+	// Device tells apart several devices in the same room. It is part of
+	// the target name and suffixed to the sensor labels that occur on more
+	// than one device of the room when the room is probed as a whole.
+	Device string `mapstructure:"device"`
+	// ScrapeInterval overrides the global interval of the background
+	// scrapes for this target.
+	ScrapeInterval time.Duration `mapstructure:"scrape_interval"`
+	// Simulation configures the readings served with --simulate.
+	Simulation Simulation `mapstructure:"simulation"`
+	// Addresses are tried in order if the device cannot be reached at IP,
+	// e.g. a secondary management address.
+	Addresses []string `mapstructure:"addresses"`
+	// Bounds overrides the global plausibility limits for this target.
+	Bounds *Bounds `mapstructure:"bounds"`
+	// PushToken authenticates readings pushed by the device. Pushes are
+	// rejected if no token is configured.
+	PushToken string `mapstructure:"push_token"`
+	// Priority is the class of the target when background scrapes queue
+	// for a free slot, see the Priority* constants.
+	Priority string `mapstructure:"priority"`
+	// SmoothingAlpha overrides the global smoothing factor of the moving
+	// average of the readings.
+	SmoothingAlpha *float64 `mapstructure:"smoothing_alpha"`
+	// Thresholds overrides the global temperature limits of the alerting
+	// rules of this target.
+	Thresholds *Thresholds `mapstructure:"thresholds"`
+}
+
+// Name returns the name used to identify the target in labels and logs.
+func (t Target) Name() string {
+	if t.Room != "" && t.Device != "" {
+		return t.Room + "/" + t.Device
+	}
+	if t.Room != "" {
+		return t.Room
+	}
+	return t.IP
+}
+
+// Interval returns the background scrape interval of the target, falling
+// back to the global default if no override is configured.
+func (t Target) Interval(global time.Duration) time.Duration {
+	if t.ScrapeInterval > 0 {
+		return t.ScrapeInterval
+	}
+	return global
+}
+
+// Smoothing returns the smoothing factor of the moving average of the
+// readings of the target, falling back to the global default if no
+// override is configured.
+func (t Target) Smoothing(global float64) float64 {
+	if t.SmoothingAlpha != nil {
+		return *t.SmoothingAlpha
+	}
+	return global
+}
+
+// AlertThresholds returns the temperature limits of the alerting rules of
+// the target, falling back to the global defaults for limits without an
+// override.
+func (t Target) AlertThresholds(global Thresholds) Thresholds {
+	if t.Thresholds == nil {
+		return global
+	}
+	result := *t.Thresholds
+	if result.Warning == nil {
+		result.Warning = global.Warning
+	}
+	if result.Critical == nil {
+		result.Critical = global.Critical
+	}
+	return result
+}
+
+// Supported priority classes of targets. Queued scrapes of higher classes
+// are started first.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// Rank returns the position of the priority class of the target, 0 being
+// the highest. Targets without a class are of normal priority.
+func (t Target) Rank() int {
+	switch t.Priority {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// Validate checks the target for invalid settings.
+func (t Target) Validate() error {
+	switch t.Priority {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+	default:
+		return fmt.Errorf("invalid priority %q of target %s, must be one of %s, %s or %s", t.Priority, t.Name(), PriorityHigh, PriorityNormal, PriorityLow)
+	}
+	if t.ScrapeInterval < 0 {
+		return fmt.Errorf("invalid scrape_interval %s of target %s, must not be negative", t.ScrapeInterval, t.Name())
+	}
+	if t.SmoothingAlpha != nil && (*t.SmoothingAlpha < 0 || *t.SmoothingAlpha > 1) {
+		return fmt.Errorf("invalid smoothing_alpha %g of target %s, must be between 0 and 1", *t.SmoothingAlpha, t.Name())
+	}
+	return nil
+}
+
+// Bounds are plausibility limits for sensor readings. Readings outside of
+// them are dropped. Unset limits are not checked.
+type Bounds struct {
+	Min *float64 `mapstructure:"min"`
+	Max *float64 `mapstructure:"max"`
+}
+
+// Contains reports whether the value lies within the bounds.
+func (b Bounds) Contains(value float64) bool {
+	if b.Min != nil && value < *b.Min {
+		return false
+	}
+	if b.Max != nil && value > *b.Max {
+		return false
+	}
+	return true
+}
+
+// Thresholds are the temperature limits, in the unit of the exported
+// readings, alerted on by the generated alerting rules. Unset limits are
+// not alerted on.
+type Thresholds struct {
+	Warning  *float64 `mapstructure:"warning"`
+	Critical *float64 `mapstructure:"critical"`
+}
+
+// Options control how the readings of a target are collected and exported.
+type Options struct {
+	// ErrorValues selects how absent or unparsable sensor values are
+	// exported, see the ErrorValues* constants.
+	ErrorValues string `mapstructure:"error_values"`
+	// ErrorTokens are additional placeholders reported by the firmware
+	// for channels without a probe, besides "--" and the variants of the
+	// known firmware languages like "n/a" or "k.A.". Other values that are
+	// no number are counted as parse failures.
+	ErrorTokens []string `mapstructure:"error_tokens"`
+	// Bounds are the plausibility limits of the readings.
+	Bounds Bounds `mapstructure:"bounds"`
+	// IntegerValues reads the integer "value x 10" branch of the devices,
+	// avoiding any locale dependent parsing.
+	IntegerValues bool `mapstructure:"integer_values"`
+	// NormalizeUnit reads the unit configured on the device and converts
+	// all readings to degrees Celsius.
+	NormalizeUnit bool `mapstructure:"normalize_unit"`
+	// LowercaseLabels lowercases the room label of the exported metrics.
+	LowercaseLabels bool `mapstructure:"lowercase_labels"`
+	// SensorLabels selects whether the sensor label is the name configured
+	// on the device or the channel number, see the SensorLabels* constants.
+	SensorLabels string `mapstructure:"sensor_labels"`
+	// SensorLabelName is the name of the label carrying the sensor of
+	// the readings, e.g. "channel" or "probe" to match other exporters.
+	SensorLabelName string `mapstructure:"sensor_label_name"`
+	// SensorIndexBase is the number of the first sensor channel.
+	SensorIndexBase int `mapstructure:"sensor_index_base"`
+	// DeviceType selects the measurement types of the sensor channels and
+	// with them the exported metric families, see the DeviceType* constants.
+	DeviceType string `mapstructure:"device_type"`
+	// MetricNames selects between the legacy wut_temperature and the unit
+	// suffixed metric names, see the MetricNames* constants.
+	MetricNames string `mapstructure:"metric_names"`
+	// MetricHelp overrides the help texts of metric families by name,
+	// e.g. to document the sensors of an installation.
+	MetricHelp map[string]string `mapstructure:"metric_help"`
+	// ClockOffset reads the device clock and exports its offset against
+	// the exporter clock.
+	ClockOffset bool `mapstructure:"clock_offset"`
+	// DerivedMetrics exports the heat index and the absolute humidity of
+	// devices measuring both temperature and relative humidity.
+	DerivedMetrics bool `mapstructure:"derived_metrics"`
+	// Profiles lists optional sets of additional OIDs walked on every
+	// scrape, see the collector package.
+	Profiles []string `mapstructure:"profiles"`
+	// DNSCacheTTL is the time the addresses of target host names are
+	// cached. Host names are resolved on every scrape if 0.
+	DNSCacheTTL time.Duration `mapstructure:"dns_cache_ttl"`
+	// MaxVarbinds is the maximum number of varbinds walked per scrape of a
+	// target. Scrapes exceeding it are truncated and export the readings
+	// received until then as a partial result. Unlimited if 0.
+	MaxVarbinds int `mapstructure:"max_varbinds"`
+}
+
+// DefaultOptions returns the options used by the exporter if nothing else
+// is configured.
+func DefaultOptions() Options {
+	return Options{
+		ErrorValues:     ErrorValuesSkip,
+		NormalizeUnit:   true,
+		LowercaseLabels: true,
+		SensorLabels:    SensorLabelsName,
+		SensorLabelName: "sensor",
+		SensorIndexBase: 1,
+		DeviceType:      DeviceTypeAuto,
+		MetricNames:     MetricNamesLegacy,
+		MaxVarbinds:     10000,
+	}
+}
+
+// Supported modes of handling absent or unparsable sensor values.
+const (
+	// ErrorValuesSkip omits the sensor from the temperature metric.
+	ErrorValuesSkip = "skip"
+	// ErrorValuesNaN exports the temperature of the sensor as NaN.
+	ErrorValuesNaN = "nan"
+	// ErrorValuesMetric exports wut_sensor_error for the sensor instead.
+	ErrorValuesMetric = "metric"
+)
+
+// Supported sources of the sensor label.
+const (
+	// SensorLabelsName uses the sensor name configured on the device and
+	// falls back to the channel number for unnamed sensors.
+	SensorLabelsName = "name"
+	// SensorLabelsIndex always uses the channel number.
+	SensorLabelsIndex = "index"
+)
+
+// Supported device types, which determine the measurement type of every
+// sensor channel.
+const (
+	// DeviceTypeAuto detects the type of every channel from the unit the
+	// firmware formats into its values, and falls back to temperature.
+	DeviceTypeAuto = "auto"
+	// DeviceTypeThermometer exports all channels as temperatures, as
+	// expected by dashboards predating the other metric families.
+	DeviceTypeThermometer = "thermometer"
+	// DeviceTypeThermoHygrometer reports the temperature on the first and
+	// the relative humidity on the second channel.
+	DeviceTypeThermoHygrometer = "thermo-hygrometer"
+	// DeviceTypeThermoHygrobarometer additionally reports the air pressure
+	// on the third channel.
+	DeviceTypeThermoHygrobarometer = "thermo-hygrobarometer"
+	// DeviceTypeAnalog exports all channels as analog inputs, scaled as
+	// configured on the device.
+	DeviceTypeAnalog = "analog"
+)
+
+// Supported naming schemes of the temperature metrics.
+const (
+	// MetricNamesLegacy exports wut_temperature.
+	MetricNamesLegacy = "legacy"
+	// MetricNamesUnit exports wut_temperature_celsius or a name suffixed
+	// with the unit reported by the device.
+	MetricNamesUnit = "unit"
+	// MetricNamesBoth exports both names to ease migrating dashboards.
+	MetricNamesBoth = "both"
+)
+
+// SensorLabel returns the name of the label carrying the sensor.
+func (o Options) SensorLabel() string {
+	if o.SensorLabelName == "" {
+		return "sensor"
+	}
+	return o.SensorLabelName
+}
+
+// Validate checks the options for invalid settings. Profile names are
+// checked by the collector package, which defines them.
+func (o Options) Validate() error {
+	switch o.ErrorValues {
+	case ErrorValuesSkip, ErrorValuesNaN, ErrorValuesMetric:
+	default:
+		return fmt.Errorf("invalid error_values %q, must be one of %s, %s or %s", o.ErrorValues, ErrorValuesSkip, ErrorValuesNaN, ErrorValuesMetric)
+	}
+	switch o.SensorLabels {
+	case SensorLabelsName, SensorLabelsIndex:
+	default:
+		return fmt.Errorf("invalid sensor_labels %q, must be %s or %s", o.SensorLabels, SensorLabelsName, SensorLabelsIndex)
+	}
+	switch o.DeviceType {
+	case DeviceTypeAuto, DeviceTypeThermometer, DeviceTypeThermoHygrometer, DeviceTypeThermoHygrobarometer, DeviceTypeAnalog:
+	default:
+		return fmt.Errorf("invalid device_type %q, must be one of %s, %s, %s, %s or %s", o.DeviceType, DeviceTypeAuto, DeviceTypeThermometer, DeviceTypeThermoHygrometer, DeviceTypeThermoHygrobarometer, DeviceTypeAnalog)
+	}
+	switch o.MetricNames {
+	case MetricNamesLegacy, MetricNamesUnit, MetricNamesBoth:
+	default:
+		return fmt.Errorf("invalid metric_names %q, must be one of %s, %s or %s", o.MetricNames, MetricNamesLegacy, MetricNamesUnit, MetricNamesBoth)
+	}
+	if !model.LabelName(o.SensorLabelName).IsValidLegacy() || o.SensorLabelName == "room" || o.SensorLabelName == "reason" {
+		return fmt.Errorf("invalid sensor_label_name %q, must be a label name other than room and reason", o.SensorLabelName)
+	}
+	if o.SensorIndexBase != 0 && o.SensorIndexBase != 1 {
+		return fmt.Errorf("invalid sensor_index_base %d, must be 0 or 1", o.SensorIndexBase)
+	}
+	if o.MaxVarbinds < 0 {
+		return fmt.Errorf("invalid max_varbinds %d, must not be negative", o.MaxVarbinds)
+	}
+	return nil
+}
+
+// Simulation configures the synthetic readings served for a target when
+// running with --simulate.
+type Simulation struct {
+	// Sensors lists the sensor labels to generate. Defaults to a single
+	// sensor named "Sensor 1".
+	Sensors []string `mapstructure:"sensors"`
+	// Base is the mean temperature in degrees Celsius, 21 if unset.
+	Base *float64 `mapstructure:"base"`
+	// Amplitude of the sinusoidal variation around the base, 1.5 if
+	// unset. 0 generates a flat line.
+	Amplitude *float64 `mapstructure:"amplitude"`
+	// Period of the sinusoidal variation.
+	Period time.Duration `mapstructure:"period"`
+	// Noise is the maximum random deviation added to every reading, 0.2
+	// if unset. 0 generates deterministic readings.
+	Noise *float64 `mapstructure:"noise"`
+}
+
+// WithDefaults returns a copy of the simulation with unset fields replaced
+// by plausible server room values.
+func (s Simulation) WithDefaults() Simulation {
+	if len(s.Sensors) == 0 {
+		s.Sensors = []string{"Sensor 1"}
+	}
+	if s.Base == nil {
+		s.Base = float64Pointer(21)
+	}
+	if s.Amplitude == nil {
+		s.Amplitude = float64Pointer(1.5)
+	}
+	if s.Period == 0 {
+		s.Period = time.Hour
+	}
+	if s.Noise == nil {
+		s.Noise = float64Pointer(0.2)
+	}
+	return s
+}
+
+func float64Pointer(value float64) *float64 {
+	return &value
+}
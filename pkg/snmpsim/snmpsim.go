@@ -0,0 +1,269 @@
+// Package snmpsim is a minimal SNMP agent replaying recorded walk data. It
+// answers SNMPv1 and SNMPv2c Get and GetNext requests, which is all the
+// collector uses, and is meant for integration tests and local development
+// without access to a physical W&T device.
+//
+// Recordings use the .snmprec format of snmpsim, one "OID|TYPE|VALUE" line
+// per varbind, so fixtures recorded with snmpsim can be replayed as is.
+package snmpsim
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// Agent serves the recorded varbinds over UDP.
+type Agent struct {
+	// Community is the community string required by requests. Requests
+	// with any other community are dropped like by real devices.
+	Community string
+
+	pdus []gosnmp.SnmpPDU
+	conn net.PacketConn
+	wg   sync.WaitGroup
+}
+
+// New returns an agent serving the varbinds.
+func New(community string, pdus []gosnmp.SnmpPDU) *Agent {
+	pdus = slices.Clone(pdus)
+	for i := range pdus {
+		pdus[i].Name = "." + strings.TrimPrefix(pdus[i].Name, ".")
+	}
+	slices.SortFunc(pdus, func(a, b gosnmp.SnmpPDU) int {
+		return compareOIDs(a.Name, b.Name)
+	})
+	return &Agent{Community: community, pdus: pdus}
+}
+
+// Listen opens the UDP socket of the agent and serves requests in the
+// background until Close is called. Use "127.0.0.1:0" to pick a free port.
+func (a *Agent) Listen(address string) error {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return err
+	}
+	a.conn = conn
+	a.wg.Add(1)
+	go a.serve()
+	return nil
+}
+
+// Addr returns the address the agent listens on.
+func (a *Agent) Addr() net.Addr {
+	return a.conn.LocalAddr()
+}
+
+// Close stops the agent.
+func (a *Agent) Close() error {
+	err := a.conn.Close()
+	a.wg.Wait()
+	return err
+}
+
+func (a *Agent) serve() {
+	defer a.wg.Done()
+	buf := make([]byte, 65535)
+	decoder := &gosnmp.GoSNMP{Logger: gosnmp.NewLogger(nil)}
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		request, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil || request.Community != a.Community {
+			continue
+		}
+		response, err := a.handle(request).MarshalMsg()
+		if err != nil {
+			continue
+		}
+		a.conn.WriteTo(response, addr)
+	}
+}
+
+// handle answers a single request.
+func (a *Agent) handle(request *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	response := &gosnmp.SnmpPacket{
+		Version:   request.Version,
+		Community: request.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: request.RequestID,
+	}
+	for i, variable := range request.Variables {
+		var (
+			pdu gosnmp.SnmpPDU
+			ok  bool
+		)
+		switch request.PDUType {
+		case gosnmp.GetRequest:
+			pdu, ok = a.get(variable.Name)
+		case gosnmp.GetNextRequest:
+			pdu, ok = a.next(variable.Name)
+		default:
+			response.Error = gosnmp.GenErr
+			response.ErrorIndex = uint8(i + 1)
+			response.Variables = request.Variables
+			return response
+		}
+		if !ok {
+			if request.Version == gosnmp.Version1 {
+				response.Error = gosnmp.NoSuchName
+				response.ErrorIndex = uint8(i + 1)
+				response.Variables = request.Variables
+				return response
+			}
+			pdu = gosnmp.SnmpPDU{Name: variable.Name, Type: gosnmp.NoSuchObject}
+			if request.PDUType == gosnmp.GetNextRequest {
+				pdu.Type = gosnmp.EndOfMibView
+			}
+		}
+		response.Variables = append(response.Variables, pdu)
+	}
+	return response
+}
+
+// get returns the varbind of the OID.
+func (a *Agent) get(oid string) (gosnmp.SnmpPDU, bool) {
+	i, found := slices.BinarySearchFunc(a.pdus, oid, func(pdu gosnmp.SnmpPDU, oid string) int {
+		return compareOIDs(pdu.Name, oid)
+	})
+	if !found {
+		return gosnmp.SnmpPDU{}, false
+	}
+	return a.pdus[i], true
+}
+
+// next returns the first varbind following the OID.
+func (a *Agent) next(oid string) (gosnmp.SnmpPDU, bool) {
+	i, found := slices.BinarySearchFunc(a.pdus, oid, func(pdu gosnmp.SnmpPDU, oid string) int {
+		return compareOIDs(pdu.Name, oid)
+	})
+	if found {
+		i++
+	}
+	if i >= len(a.pdus) {
+		return gosnmp.SnmpPDU{}, false
+	}
+	return a.pdus[i], true
+}
+
+// compareOIDs orders OIDs numerically by their components.
+func compareOIDs(a, b string) int {
+	as := strings.Split(strings.Trim(a, "."), ".")
+	bs := strings.Split(strings.Trim(b, "."), ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.ParseUint(as[i], 10, 64)
+		y, _ := strconv.ParseUint(bs[i], 10, 64)
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// LoadFile reads a recording in the .snmprec format.
+func LoadFile(path string) ([]gosnmp.SnmpPDU, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load parses a recording in the .snmprec format. Empty lines and lines
+// starting with # are ignored. Types suffixed with "x" carry hex encoded
+// values.
+func Load(r io.Reader) ([]gosnmp.SnmpPDU, error) {
+	var pdus []gosnmp.SnmpPDU
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		pdu, err := parseRecord(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pdus = append(pdus, pdu)
+	}
+	return pdus, scanner.Err()
+}
+
+// parseRecord parses a single "OID|TYPE|VALUE" line.
+func parseRecord(text string) (gosnmp.SnmpPDU, error) {
+	parts := strings.SplitN(text, "|", 3)
+	if len(parts) != 3 {
+		return gosnmp.SnmpPDU{}, errors.New("expected OID|TYPE|VALUE")
+	}
+	oid, tag, value := parts[0], parts[1], parts[2]
+	encoded := strings.HasSuffix(tag, "x")
+	tag = strings.TrimSuffix(tag, "x")
+	if encoded {
+		decoded, err := hex.DecodeString(value)
+		if err != nil {
+			return gosnmp.SnmpPDU{}, fmt.Errorf("invalid hex value: %w", err)
+		}
+		value = string(decoded)
+	}
+
+	pdu := gosnmp.SnmpPDU{Name: oid}
+	var err error
+	switch tag {
+	case "2":
+		pdu.Type = gosnmp.Integer
+		pdu.Value, err = strconv.Atoi(value)
+	case "4":
+		pdu.Type = gosnmp.OctetString
+		pdu.Value = []byte(value)
+	case "5":
+		pdu.Type = gosnmp.Null
+	case "6":
+		pdu.Type = gosnmp.ObjectIdentifier
+		pdu.Value = value
+	case "64":
+		pdu.Type = gosnmp.IPAddress
+		pdu.Value = value
+	case "65":
+		pdu.Type = gosnmp.Counter32
+		pdu.Value, err = parseUint(value, 32)
+	case "66":
+		pdu.Type = gosnmp.Gauge32
+		pdu.Value, err = parseUint(value, 32)
+	case "67":
+		pdu.Type = gosnmp.TimeTicks
+		pdu.Value, err = parseUint(value, 32)
+	case "70":
+		var counter uint64
+		counter, err = strconv.ParseUint(value, 10, 64)
+		pdu.Type, pdu.Value = gosnmp.Counter64, counter
+	default:
+		return gosnmp.SnmpPDU{}, fmt.Errorf("unsupported type %s", parts[1])
+	}
+	if err != nil {
+		return gosnmp.SnmpPDU{}, fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	return pdu, nil
+}
+
+func parseUint(value string, bits int) (uint32, error) {
+	v, err := strconv.ParseUint(value, 10, bits)
+	return uint32(v), err
+}
@@ -0,0 +1,56 @@
+package snmpsim
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+func TestLoad(t *testing.T) {
+	pdus, err := Load(strings.NewReader(`
+# comment
+1.3.6.1.2.1.1.1.0|4|Web-Thermometer
+1.3.6.1.2.1.1.3.0|67|42
+1.3.6.1.4.1.5040.1.2.6.3.1.5.1.0|4x|c2b043
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdus) != 3 {
+		t.Fatalf("expected 3 PDUs, got %d", len(pdus))
+	}
+	if pdus[1].Type != gosnmp.TimeTicks || pdus[1].Value != uint32(42) {
+		t.Errorf("unexpected TimeTicks PDU %+v", pdus[1])
+	}
+	if string(pdus[2].Value.([]byte)) != "°C" {
+		t.Errorf("unexpected hex decoded value %q", pdus[2].Value)
+	}
+
+	if _, err := Load(strings.NewReader("1.3.6|99|x")); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestNextOrdersNumerically(t *testing.T) {
+	agent := New("public", []gosnmp.SnmpPDU{
+		{Name: "1.3.6.1.10", Type: gosnmp.Integer, Value: 10},
+		{Name: "1.3.6.1.9", Type: gosnmp.Integer, Value: 9},
+		{Name: "1.3.6.2", Type: gosnmp.Integer, Value: 2},
+	})
+
+	var walked []string
+	oid := ".1.3.6.1"
+	for {
+		pdu, ok := agent.next(oid)
+		if !ok {
+			break
+		}
+		walked = append(walked, pdu.Name)
+		oid = pdu.Name
+	}
+	expected := ".1.3.6.1.9 .1.3.6.1.10 .1.3.6.2"
+	if got := strings.Join(walked, " "); got != expected {
+		t.Errorf("walked %s, want %s", got, expected)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// RoutePolicy restricts the requests to the paths below a prefix by
+// network and credentials.
+type RoutePolicy struct {
+	// Path is the prefix of the paths the policy applies to, including
+	// the --web.route-prefix. It matches the path itself and the paths
+	// below it. The policy with the longest matching prefix applies.
+	Path string `mapstructure:"path"`
+	// AllowedNetworks lists the networks in CIDR notation requests are
+	// accepted from. Requests from all networks are accepted if empty.
+	AllowedNetworks []string `mapstructure:"allowed_networks"`
+	// BearerToken, BasicAuth and ClientCert are the accepted credentials.
+	// If any is set, requests have to authenticate with one of them.
+	BearerToken string     `mapstructure:"bearer_token"`
+	BasicAuth   *BasicAuth `mapstructure:"basic_auth"`
+	// ClientCert accepts a client certificate verified by the client CAs
+	// of the TLS listener.
+	ClientCert bool `mapstructure:"client_cert"`
+	// ClientNames restricts ClientCert to certificates with one of the
+	// names as common name or DNS, email, IP or URI subject alternative
+	// name. Setting it implies ClientCert.
+	ClientNames []string `mapstructure:"client_names"`
+}
+
+// validate checks that the policy has a path, valid networks and complete
+// credentials.
+func (p RoutePolicy) validate() error {
+	if !strings.HasPrefix(p.Path, "/") {
+		return fmt.Errorf("invalid path %q of web.routes, must start with /", p.Path)
+	}
+	for _, network := range p.AllowedNetworks {
+		if _, err := netip.ParsePrefix(network); err != nil {
+			return fmt.Errorf("invalid allowed_networks of route %s: %w", p.Path, err)
+		}
+	}
+	if p.BasicAuth != nil && (p.BasicAuth.Username == "" || p.BasicAuth.Password == "") {
+		return fmt.Errorf("basic_auth of route %s requires username and password", p.Path)
+	}
+	return nil
+}
+
+// allowedFrom reports whether the policy accepts requests from the remote
+// address of the request.
+func (p RoutePolicy) allowedFrom(r *http.Request) bool {
+	if len(p.AllowedNetworks) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	for _, network := range p.AllowedNetworks {
+		if prefix, err := netip.ParsePrefix(network); err == nil && prefix.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiresCredentials reports whether requests have to authenticate.
+func (p RoutePolicy) requiresCredentials() bool {
+	return p.BearerToken != "" || p.BasicAuth != nil || p.ClientCert || len(p.ClientNames) > 0
+}
+
+// authenticated reports whether the request carries one of the credentials
+// of the policy. Requests to policies without credentials are always
+// authenticated.
+func (p RoutePolicy) authenticated(r *http.Request) bool {
+	if !p.requiresCredentials() {
+		return true
+	}
+	if p.BearerToken != "" {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(provided), []byte(p.BearerToken)) == 1 {
+			return true
+		}
+	}
+	if p.BasicAuth != nil && p.BasicAuth.check(r) {
+		return true
+	}
+	if (!p.ClientCert && len(p.ClientNames) == 0) || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return false
+	}
+	return len(p.ClientNames) == 0 || slices.ContainsFunc(certificateNames(r.TLS.VerifiedChains[0][0]), func(name string) bool {
+		return slices.Contains(p.ClientNames, name)
+	})
+}
+
+// certificateNames returns the common name and subject alternative names
+// of the certificate.
+func certificateNames(cert *x509.Certificate) []string {
+	names := []string{cert.Subject.CommonName}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return slices.DeleteFunc(names, func(name string) bool { return name == "" })
+}
+
+// policy returns the policy with the longest prefix of the path.
+func (c WebConfig) policy(path string) (RoutePolicy, bool) {
+	var match RoutePolicy
+	found := false
+	for _, p := range c.Routes {
+		if hasPathPrefix(path, p.Path) && (!found || len(p.Path) > len(match.Path)) {
+			match, found = p, true
+		}
+	}
+	return match, found
+}
+
+// routesHandler enforces the route policies before passing requests to
+// next.
+func (c WebConfig) routesHandler(next http.Handler) http.Handler {
+	if len(c.Routes) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := c.policy(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !policy.allowedFrom(r) {
+			writeError(w, http.StatusForbidden, apiError{Code: errorCodeForbidden, Message: "Forbidden", Hint: "the path is not served to your network"})
+			return
+		}
+		if !policy.authenticated(r) {
+			if policy.BasicAuth != nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="wut-temperature-exporter"`)
+			} else if policy.BearerToken != "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wut-temperature-exporter"`)
+			}
+			writeError(w, http.StatusUnauthorized, apiError{Code: errorCodeUnauthorized, Message: "Unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutePolicies(t *testing.T) {
+	web := WebConfig{Routes: []RoutePolicy{
+		{Path: "/", AllowedNetworks: []string{"10.0.5.0/24"}},
+		{Path: "/-/", BearerToken: "secret"},
+		{Path: "/probe", BearerToken: "secret"},
+	}}
+	handler := web.routesHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path, remote, token string
+		status              int
+	}{
+		{"/?target=server", "10.0.5.7:40000", "", http.StatusOK},
+		{"/?target=server", "192.0.2.1:40000", "", http.StatusForbidden},
+		{"/-/reload", "192.0.2.1:40000", "", http.StatusUnauthorized},
+		{"/-/reload", "192.0.2.1:40000", "secret", http.StatusOK},
+		{"/probe", "192.0.2.1:40000", "", http.StatusUnauthorized},
+		{"/probes", "10.0.5.7:40000", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, test.path, nil)
+		r.RemoteAddr = test.remote
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s from %s: expected %d, got %d", test.path, test.remote, test.status, w.Code)
+		}
+	}
+}
+
+func TestRoutePolicyClientNames(t *testing.T) {
+	web := WebConfig{Routes: []RoutePolicy{
+		{Path: "/", ClientNames: []string{"prometheus.example.edu", "admin"}},
+		{Path: "/-/", ClientNames: []string{"admin"}},
+	}}
+	handler := web.routesHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	prometheus := &x509.Certificate{Subject: pkix.Name{CommonName: "scraper"}, DNSNames: []string{"prometheus.example.edu"}}
+	admin := &x509.Certificate{Subject: pkix.Name{CommonName: "admin"}}
+	tests := []struct {
+		path   string
+		cert   *x509.Certificate
+		status int
+	}{
+		{"/?target=server", nil, http.StatusUnauthorized},
+		{"/?target=server", prometheus, http.StatusOK},
+		{"/?target=server", admin, http.StatusOK},
+		{"/-/reload", prometheus, http.StatusUnauthorized},
+		{"/-/reload", admin, http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, test.path, nil)
+		if test.cert != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{test.cert}}}
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s with certificate %v: expected %d, got %d", test.path, test.cert != nil, test.status, w.Code)
+		}
+	}
+}
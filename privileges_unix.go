@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"syscall"
+)
+
+// dropPrivileges switches all threads to the user and group, clearing the
+// supplementary groups.
+func dropPrivileges(uid, gid int) error {
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return err
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return err
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return err
+	}
+	if uid != 0 && syscall.Setuid(0) == nil {
+		return errors.New("regained root privileges after dropping them")
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package main
+
+import "errors"
+
+// dropPrivileges is not supported on Windows, run the exporter as a
+// service account instead.
+func dropPrivileges(uid, gid int) error {
+	return errors.New("hardening user and group are not supported on Windows")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// probeHandler serves the metrics of the target passed in the target
+// parameter. Targets are scraped on every request unless a poller is given,
+// whose cached results are served instead.
+func probeHandler(store *configStore, poller *poller, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveProbe(w, r, store.Get(), poller, nil, requestLogger(r.Context(), fallback))
+	}
+}
+
+// serveProbe serves the metrics of the target of the request among those of
+// the configuration, with the labels added to all metrics. A room with
+// several devices is served as a whole.
+func serveProbe(w http.ResponseWriter, r *http.Request, config config, poller *poller, labels prometheus.Labels, logger *zap.Logger) {
+	query := r.URL.Query()
+
+	target := query.Get("target")
+	if len(query["target"]) != 1 || target == "" {
+		writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "'target' parameter must be specified once", Hint: "pass the room or IP of a configured target"})
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true, DisableCompression: true})
+	registerer := prometheus.WrapRegistererWith(labels, registry)
+
+	targets := config.findTargets(target)
+	if len(targets) == 0 {
+		logger.Error("No target found", zap.String("target", target))
+		writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Target not found", Target: target, Hint: "pass the room or IP of a configured target"})
+		return
+	}
+	if len(targets) > 1 {
+		if serveRoom(w, r, config, poller, targets, registerer, logger) {
+			h.ServeHTTP(w, r)
+		}
+		return
+	}
+
+	t := targets[0]
+	if poller != nil {
+		result, ok := poller.Result(t)
+		if !ok {
+			writeError(w, http.StatusServiceUnavailable, apiError{Code: errorCodeNotScraped, Message: "Target has not been scraped yet", Target: target, Hint: "retry after the first background scrape"})
+			return
+		}
+		if result.Err != nil {
+			writeScrapeError(w, target, result.Err)
+			return
+		}
+		c := config.collector(t, logger)
+		now := time.Now()
+		result, stale := config.applyMaxAge(c, result, now, poller.started)
+		metrics := append(c.Timestamped(result), stale...)
+		registerer.MustRegister(staticCollector(append(metrics, poller.Aggregates(c, t, now)...)))
+	} else {
+		c := config.collector(t, logger)
+		result := c.Scrape(r.Context())
+		if deadlineExceeded(r.Context()) {
+			logger.Error("Probe timed out", zap.String("target", target))
+			writeError(w, http.StatusGatewayTimeout, apiError{Code: errorCodeSNMPTimeout, Message: "Probe timed out", Target: target, Hint: "check that the device is reachable via SNMP"})
+			return
+		}
+		if result.Err != nil {
+			logger.Error("Error scraping SNMP target", zap.String("ip", t.IP), zap.String("reason", collector.Reason(result.Err)), zap.Error(result.Err))
+			writeScrapeError(w, target, result.Err)
+			return
+		}
+		registerer.MustRegister(staticCollector(c.Metrics(result)))
+	}
+	h.ServeHTTP(w, r)
+}
+
+// deadlineExceeded reports whether the deadline of the context has passed.
+// Unlike ctx.Err it also holds when SNMP reads timed out on the deadline
+// before the context was cancelled.
+func deadlineExceeded(ctx context.Context) bool {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
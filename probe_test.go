@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+	"github.com/hm-edu/wut-temperature-exporter/pkg/snmpsim"
+)
+
+// startAgent replays the recorded walk of a Web-Thermometer and returns the
+// configuration probing it.
+func startAgent(t *testing.T, community string) config {
+	t.Helper()
+	pdus, err := snmpsim.LoadFile("testdata/wut-57613.snmprec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := snmpsim.New(community, pdus)
+	if err := agent.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { agent.Close() })
+
+	return config{
+		Targets:      []wutconfig.Target{{IP: agent.Addr().String(), Room: "Server"}},
+		Community:    "public",
+		ProbeTimeout: 10 * time.Second,
+		Options:      wutconfig.DefaultOptions(),
+	}
+}
+
+// probe requests the target from the probe endpoint.
+func probe(t *testing.T, handler http.Handler, target string, header http.Header) (int, string) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/?target="+target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func assertLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in response:\n%s", line, body)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	store := newConfigStore(startAgent(t, "public"), zap.NewNop())
+	handler := probeTimeout(store, probeHandler(store, nil, zap.NewNop()))
+
+	status, body := probe(t, handler, "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body,
+		`wut_temperature{room="server",sensor="Rack 1"} 21.4`,
+		`wut_temperature{room="server",sensor="Rack 2"} 23.1`,
+		`wut_sensor_connected{room="server",sensor="Rack 1"} 1`,
+		`wut_sensor_connected{room="server",sensor="3"} 0`,
+		`wut_scrape_partial 0`,
+	)
+}
+
+func TestProbeIntegerValues(t *testing.T) {
+	config := startAgent(t, "public")
+	config.IntegerValues = true
+	config.SensorLabels = wutconfig.SensorLabelsIndex
+	store := newConfigStore(config, zap.NewNop())
+
+	status, body := probe(t, probeHandler(store, nil, zap.NewNop()), "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body,
+		`wut_temperature{room="server",sensor="1"} 21.4`,
+		`wut_temperature{room="server",sensor="2"} 23.1`,
+	)
+}
+
+func TestProbeDaemon(t *testing.T) {
+	config := startAgent(t, "public")
+	store := newConfigStore(config, zap.NewNop())
+	poller := newPoller(context.Background(), config, zap.NewNop())
+	handler := probeHandler(store, poller, zap.NewNop())
+
+	if status, _ := probe(t, handler, "server", nil); status != http.StatusServiceUnavailable {
+		t.Errorf("expected %d before the first scrape, got %d", http.StatusServiceUnavailable, status)
+	}
+
+	poller.scrape(config, config.Targets[0])
+	status, body := probe(t, handler, "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	// Cached results carry the time of the scrape.
+	if !strings.Contains(body, `wut_temperature{room="server",sensor="Rack 1"} 21.4 `) {
+		t.Errorf("missing timestamped reading in response:\n%s", body)
+	}
+}
+
+func TestProbeUnknownTarget(t *testing.T) {
+	store := newConfigStore(startAgent(t, "public"), zap.NewNop())
+
+	status, body := probe(t, probeHandler(store, nil, zap.NewNop()), "kitchen", nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	var apiErr apiError
+	if err := json.Unmarshal([]byte(body), &apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Code != errorCodeUnknownTarget || apiErr.Target != "kitchen" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestProbeTimeout(t *testing.T) {
+	// The agent drops requests with the wrong community like a device.
+	store := newConfigStore(startAgent(t, "private"), zap.NewNop())
+	handler := probeTimeout(store, probeHandler(store, nil, zap.NewNop()))
+
+	start := time.Now()
+	status, body := probe(t, handler, "server", http.Header{"X-Prometheus-Scrape-Timeout-Seconds": {"1"}})
+	if status != http.StatusGatewayTimeout {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("probe took %s despite the deadline", elapsed)
+	}
+}
+
+func TestProbeDaemonStale(t *testing.T) {
+	config := startAgent(t, "public")
+	config.MaxAge = time.Millisecond
+	config.StaleReadings = staleReadingsWithhold
+	store := newConfigStore(config, zap.NewNop())
+	poller := newPoller(context.Background(), config, zap.NewNop())
+	handler := probeHandler(store, poller, zap.NewNop())
+
+	poller.scrape(config, config.Targets[0])
+	time.Sleep(2 * config.MaxAge)
+	status, body := probe(t, handler, "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body, `wut_data_stale{room="server",sensor="Rack 1"} 1`)
+	if strings.Contains(body, "wut_temperature{") {
+		t.Errorf("stale readings served:\n%s", body)
+	}
+}
+
+func TestProbeDaemonRestore(t *testing.T) {
+	config := startAgent(t, "public")
+	path := filepath.Join(t.TempDir(), "state.json")
+	previous := newPoller(context.Background(), config, zap.NewNop())
+	previous.scrape(config, config.Targets[0])
+	if err := previous.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newConfigStore(config, zap.NewNop())
+	poller := newPoller(context.Background(), config, zap.NewNop())
+	if err := poller.Restore(path); err != nil {
+		t.Fatal(err)
+	}
+	status, body := probe(t, probeHandler(store, poller, zap.NewNop()), "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body, `wut_data_stale{room="server",sensor="Rack 1"} 1`)
+	if !strings.Contains(body, `wut_temperature{room="server",sensor="Rack 1"} 21.4 `) {
+		t.Errorf("missing restored reading in response:\n%s", body)
+	}
+}
+
+func TestProbeDaemonAggregates(t *testing.T) {
+	config := startAgent(t, "public")
+	store := newConfigStore(config, zap.NewNop())
+	poller := newPoller(context.Background(), config, zap.NewNop())
+	poller.aggregators = append(poller.aggregators, newWindowAggregates(time.Minute))
+	poller.scrape(config, config.Targets[0])
+	poller.Push(config.Targets[0], []collector.Reading{{Sensor: "Rack 1", Value: 25.4, Timestamp: time.Now()}})
+
+	status, body := probe(t, probeHandler(store, poller, zap.NewNop()), "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body,
+		`wut_temperature_min{room="server",sensor="Rack 1"} 21.4`,
+		`wut_temperature_max{room="server",sensor="Rack 1"} 25.4`,
+		`wut_temperature_avg{room="server",sensor="Rack 1"} 23.4`,
+	)
+}
+
+func TestProbeTenant(t *testing.T) {
+	config := startAgent(t, "facility")
+	config.Tenants = []TenantConfig{{
+		Name:        "facility",
+		Community:   "facility",
+		Targets:     config.Targets,
+		Labels:      map[string]string{"tenant": "facility"},
+		BearerToken: "secret",
+		BasicAuth:   &BasicAuth{Username: "facility", Password: "password"},
+	}}
+	config.Targets = nil
+	store := newConfigStore(config, zap.NewNop())
+	tenant := tenantProbeHandler(store, zap.NewNop())
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue("tenant", "facility")
+		tenant(w, r)
+	})
+
+	if status, _ := probe(t, handler, "server", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected %d without token, got %d", http.StatusUnauthorized, status)
+	}
+	status, body := probe(t, handler, "server", http.Header{"Authorization": {"Bearer secret"}})
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body, `wut_temperature{room="server",sensor="Rack 1",tenant="facility"} 21.4`)
+
+	// Basic auth is accepted as an alternative to the token.
+	basic := http.Header{"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte("facility:password"))}}
+	if status, body := probe(t, handler, "server", basic); status != http.StatusOK {
+		t.Errorf("unexpected status %d with basic auth: %s", status, body)
+	}
+}
+
+func TestTenantReservedLabels(t *testing.T) {
+	for _, name := range []string{"room", "sensor", "reason", "output", "counter", "alarm", "name", "interface", "device"} {
+		tenant := TenantConfig{Name: "facility", Labels: map[string]string{name: "x"}}
+		if err := tenant.validate("sensor"); err == nil {
+			t.Errorf("expected tenant label %q to be rejected", name)
+		}
+	}
+	tenant := TenantConfig{Name: "facility", Labels: map[string]string{"tenant": "facility"}}
+	if err := tenant.validate("sensor"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProbeFailoverAddress(t *testing.T) {
+	config := startAgent(t, "public")
+	// Nothing listens on the primary address.
+	config.Targets[0].Addresses = []string{config.Targets[0].IP}
+	config.Targets[0].IP = "127.0.0.1:1"
+	store := newConfigStore(config, zap.NewNop())
+
+	status, body := probe(t, probeHandler(store, nil, zap.NewNop()), "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body, `wut_temperature{room="server",sensor="Rack 1"} 21.4`)
+}
+
+func TestProbeRoom(t *testing.T) {
+	config := startAgent(t, "public")
+	second := startAgent(t, "public").Targets[0]
+	config.Targets[0].Device = "box1"
+	second.Device = "box2"
+	config.Targets = append(config.Targets, second)
+	store := newConfigStore(config, zap.NewNop())
+	handler := probeHandler(store, nil, zap.NewNop())
+
+	status, body := probe(t, handler, "server", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body,
+		`wut_temperature{room="server",sensor="Rack 1@box1"} 21.4`,
+		`wut_temperature{room="server",sensor="Rack 1@box2"} 21.4`,
+		`wut_scrape_partial{device="box1"} 0`,
+		`wut_scrape_partial{device="box2"} 0`,
+	)
+
+	// A single device is probed by its target name.
+	status, body = probe(t, handler, "server/box2", nil)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", status, body)
+	}
+	assertLines(t, body, `wut_temperature{room="server",sensor="Rack 1"} 21.4`)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// pushReservedKeys are parameters of a push that do not carry readings.
+var pushReservedKeys = map[string]bool{"target": true, "token": true, "alarm": true, "unit": true}
+
+// pushHandler accepts readings and alarms sent by the devices via their
+// "send to web page" action and merges them into the cached state of the
+// poller. Readings are passed as sensor=value pairs, either form encoded or
+// as JSON object. Temperatures are in the unit passed as unit parameter,
+// e.g. "F", and in degrees Celsius if it is missing, and are converted like
+// scraped readings. Every target has to configure its own push_token to
+// accept pushes.
+func pushHandler(store *configStore, poller *poller, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r.Context(), fallback)
+		if r.Method != http.MethodPost && r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, http.StatusMethodNotAllowed, apiError{Code: errorCodeMethodNotAllowed, Message: "Only GET or POST requests allowed"})
+			return
+		}
+
+		values := map[string]string{}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			var body map[string]any
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "Invalid JSON body"})
+				return
+			}
+			for key, value := range body {
+				values[key] = fmt.Sprint(value)
+			}
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, apiError{Code: errorCodeBadRequest, Message: "Invalid form body"})
+			return
+		}
+		for key := range r.Form {
+			values[key] = r.Form.Get(key)
+		}
+
+		config := store.Get()
+		target, ok := config.findTarget(values["target"])
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Target not found", Target: values["target"]})
+			return
+		}
+		token := values["token"]
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+		if target.PushToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(target.PushToken)) != 1 {
+			pushesRejected.WithLabelValues(target.Name()).Inc()
+			logger.Warn("Rejecting push with invalid token", zap.String("target", target.Name()), zap.String("client", r.RemoteAddr))
+			writeError(w, http.StatusUnauthorized, apiError{Code: errorCodeUnauthorized, Message: "Unauthorized", Target: target.Name(), Hint: "pass the push_token of the target"})
+			return
+		}
+
+		c := config.collector(target, logger)
+		deviceUnit := collector.ParseUnit(values["unit"])
+		now := time.Now()
+		var readings []collector.Reading
+		for key, raw := range values {
+			if pushReservedKeys[key] {
+				continue
+			}
+			if reading, ok := c.ParseReading(key, raw, deviceUnit, now); ok {
+				readings = append(readings, reading)
+			}
+		}
+		if alarm := values["alarm"]; alarm != "" {
+			pushAlarms.WithLabelValues(c.RoomLabel(), alarm).Inc()
+			logger.Info("Received pushed alarm", zap.String("target", target.Name()), zap.String("alarm", alarm))
+		}
+
+		poller.Push(target, readings)
+		pushesReceived.WithLabelValues(target.Name()).Inc()
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
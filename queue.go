@@ -0,0 +1,144 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// scrapeJob is a due scrape of a target waiting in the queue.
+type scrapeJob struct {
+	target   wutconfig.Target
+	enqueued time.Time
+	seq      uint64
+}
+
+// jobHeap orders jobs by the rank of their target and in order of arrival
+// within a rank.
+type jobHeap []scrapeJob
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if ri, rj := h[i].target.Rank(), h[j].target.Rank(); ri != rj {
+		return ri < rj
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x any)   { *h = append(*h, x.(scrapeJob)) }
+func (h *jobHeap) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
+
+// scrapeQueue holds the due scrapes until a worker is free. A target is
+// queued at most once, so that a slow target does not pile up scrapes.
+type scrapeQueue struct {
+	mu      sync.Mutex
+	jobs    jobHeap
+	pending map[string]bool
+	seq     uint64
+	ready   chan struct{}
+}
+
+func newScrapeQueue() *scrapeQueue {
+	return &scrapeQueue{pending: make(map[string]bool), ready: make(chan struct{}, 1)}
+}
+
+// push queues a scrape of the target. It returns false if a scrape of the
+// target is still queued or running.
+func (q *scrapeQueue) push(target wutconfig.Target) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending[target.Name()] {
+		return false
+	}
+	q.pending[target.Name()] = true
+	q.seq++
+	heap.Push(&q.jobs, scrapeJob{target: target, enqueued: time.Now(), seq: q.seq})
+	scrapeQueueDepth.Set(float64(len(q.jobs)))
+	q.signal()
+	return true
+}
+
+// pop blocks until a job is queued or the context is cancelled.
+func (q *scrapeQueue) pop(ctx context.Context) (scrapeJob, bool) {
+	for {
+		q.mu.Lock()
+		if len(q.jobs) > 0 {
+			job := heap.Pop(&q.jobs).(scrapeJob)
+			scrapeQueueDepth.Set(float64(len(q.jobs)))
+			if len(q.jobs) > 0 {
+				// Wake up the next idle worker.
+				q.signal()
+			}
+			q.mu.Unlock()
+			return job, true
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return scrapeJob{}, false
+		case <-q.ready:
+		}
+	}
+}
+
+// done marks the scrape of the target as finished.
+func (q *scrapeQueue) done(target wutconfig.Target) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.pending, target.Name())
+}
+
+func (q *scrapeQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// work runs a worker of the pool scraping queued targets until the context
+// is cancelled.
+func (p *poller) work(ctx context.Context, config config, queue *scrapeQueue, id int) {
+	worker := strconv.Itoa(id)
+	busy := workerBusy.WithLabelValues(worker)
+	scrapes := workerScrapes.WithLabelValues(worker)
+	defer workerBusy.DeleteLabelValues(worker)
+	for {
+		job, ok := queue.pop(ctx)
+		if !ok {
+			return
+		}
+		scrapeQueueWait.Observe(time.Since(job.enqueued).Seconds())
+		busy.Set(1)
+		p.scrape(config, job.target)
+		busy.Set(0)
+		scrapes.Inc()
+		queue.done(job.target)
+	}
+}
+
+// workers returns the size of the worker pool, one worker per target if
+// max_concurrent_scrapes is not set.
+func (c config) workers() int {
+	if c.MaxConcurrentScrapes > 0 {
+		return min(c.MaxConcurrentScrapes, len(c.Targets))
+	}
+	return len(c.Targets)
+}
+
+// logQueueSkip logs a due scrape skipped as the previous one is pending.
+func (p *poller) logQueueSkip(target wutconfig.Target) {
+	scrapesSkipped.WithLabelValues(target.Name()).Inc()
+	p.logger.Warn("Skipping scrape, previous scrape still queued or running", zap.String("target", target.Name()))
+}
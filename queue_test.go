@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestScrapeQueuePriority(t *testing.T) {
+	queue := newScrapeQueue()
+	lab := wutconfig.Target{Room: "lab", Priority: wutconfig.PriorityLow}
+	office := wutconfig.Target{Room: "office"}
+	ups := wutconfig.Target{Room: "ups", Priority: wutconfig.PriorityHigh}
+	for _, target := range []wutconfig.Target{lab, office, ups} {
+		queue.push(target)
+	}
+	if queue.push(lab) {
+		t.Error("queued the pending target twice")
+	}
+
+	for _, expected := range []string{"ups", "office", "lab"} {
+		job, ok := queue.pop(t.Context())
+		if !ok || job.target.Room != expected {
+			t.Fatalf("expected %s, got %+v", expected, job.target)
+		}
+	}
+	queue.done(lab)
+	if !queue.push(lab) {
+		t.Error("finished target not queued")
+	}
+}
+
+func TestScrapeQueueCancel(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, ok := newScrapeQueue().pop(ctx); ok {
+		t.Fatal("expected no job")
+	}
+}
+
+func TestScheduledTargetAdvance(t *testing.T) {
+	start := time.Unix(0, 0)
+	s := &scheduledTarget{interval: time.Minute, due: start}
+
+	s.advance(start)
+	if !s.due.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected next scrape after one interval, got %s", s.due.Sub(start))
+	}
+	// Scrapes missed while the scheduler was delayed are dropped.
+	s.advance(start.Add(3*time.Minute + 10*time.Second))
+	if !s.due.Equal(start.Add(4 * time.Minute)) {
+		t.Errorf("expected next scrape in phase after the delay, got %s", s.due.Sub(start))
+	}
+}
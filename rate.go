@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+var rateFamily = collector.NewFamily(
+	"wut_temperature_rate",
+	"Change of the temperature reading from WUT sensor between the last two readings in degrees per minute",
+	"room", "sensor",
+)
+
+// temperatureRate exports how fast the readings of every sensor change. A
+// rapid rise hints at a fire or a failed air conditioning long before any
+// absolute threshold is reached.
+type temperatureRate struct {
+	mu sync.Mutex
+	// sensors holds the last two readings of every sensor of a target.
+	sensors map[string]map[string][2]collector.Reading
+}
+
+func newTemperatureRate() *temperatureRate {
+	return &temperatureRate{sensors: make(map[string]map[string][2]collector.Reading)}
+}
+
+// Record adds the readings of the target.
+func (r *temperatureRate) Record(target string, readings []collector.Reading) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sensors, ok := r.sensors[target]
+	if !ok {
+		sensors = make(map[string][2]collector.Reading)
+		r.sensors[target] = sensors
+	}
+	for _, reading := range readings {
+		last := sensors[reading.Sensor]
+		if !reading.Timestamp.After(last[1].Timestamp) {
+			continue
+		}
+		sensors[reading.Sensor] = [2]collector.Reading{last[1], reading}
+	}
+}
+
+// Metrics returns the rate of all sensors of the target with at least two
+// readings.
+func (r *temperatureRate) Metrics(c collector.Collector, target string, now time.Time) []prometheus.Metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var metrics []prometheus.Metric
+	for sensor, last := range r.sensors[target] {
+		if last[0].Timestamp.IsZero() {
+			continue
+		}
+		rate := (last[1].Value - last[0].Value) / last[1].Timestamp.Sub(last[0].Timestamp).Minutes()
+		metrics = append(metrics, prometheus.MustNewConstMetric(c.Desc(rateFamily), prometheus.GaugeValue, rate, c.RoomLabel(), sensor))
+	}
+	return metrics
+}
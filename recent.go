@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// recorder receives every reading of the background scrapes and pushes.
+type recorder interface {
+	Record(target string, readings []collector.Reading)
+}
+
+// recentReadings keeps the last readings of every sensor in memory, e.g. to
+// draw trends without an external time series database.
+type recentReadings struct {
+	size int
+
+	mu      sync.RWMutex
+	sensors map[string]map[string]*ring
+}
+
+func newRecentReadings(size int) *recentReadings {
+	return &recentReadings{size: size, sensors: make(map[string]map[string]*ring)}
+}
+
+// ring is a fixed size buffer of readings overwriting the oldest.
+type ring struct {
+	readings []collector.Reading
+	next     int
+}
+
+func (r *ring) add(reading collector.Reading, size int) {
+	if len(r.readings) < size {
+		r.readings = append(r.readings, reading)
+		return
+	}
+	r.readings[r.next] = reading
+	r.next = (r.next + 1) % size
+}
+
+// ordered returns the readings from the oldest to the newest.
+func (r *ring) ordered() []collector.Reading {
+	return append(append([]collector.Reading{}, r.readings[r.next:]...), r.readings[:r.next]...)
+}
+
+// Record adds the readings of the target.
+func (b *recentReadings) Record(target string, readings []collector.Reading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sensors, ok := b.sensors[target]
+	if !ok {
+		sensors = make(map[string]*ring)
+		b.sensors[target] = sensors
+	}
+	for _, reading := range readings {
+		r, ok := sensors[reading.Sensor]
+		if !ok {
+			r = &ring{}
+			sensors[reading.Sensor] = r
+		}
+		r.add(reading, b.size)
+	}
+}
+
+// Get returns the buffered readings of all sensors of the target.
+func (b *recentReadings) Get(target string) map[string][]collector.Reading {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	result := make(map[string][]collector.Reading, len(b.sensors[target]))
+	for sensor, r := range b.sensors[target] {
+		result[sensor] = r.ordered()
+	}
+	return result
+}
+
+// recentPoint is a reading in responses of recentHandler.
+type recentPoint struct {
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// recentHandler serves the buffered readings of the target passed in the
+// target parameter, grouped by sensor and ordered by time.
+func (b *recentReadings) recentHandler(store *configStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target, ok := store.Get().findTarget(r.URL.Query().Get("target"))
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTarget, Message: "Target not found", Target: r.URL.Query().Get("target"), Hint: "pass the room or IP of a configured target"})
+			return
+		}
+		sensors := make(map[string][]recentPoint)
+		for sensor, readings := range b.Get(target.Name()) {
+			points := make([]recentPoint, 0, len(readings))
+			for _, reading := range readings {
+				points = append(points, recentPoint{Value: reading.Value, Timestamp: reading.Timestamp})
+			}
+			sensors[sensor] = points
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Target  string                   `json:"target"`
+			Sensors map[string][]recentPoint `json:"sensors"`
+		}{target.Name(), sensors})
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+func TestRecentReadings(t *testing.T) {
+	recent := newRecentReadings(3)
+	for i := range 5 {
+		recent.Record("server", []collector.Reading{{Sensor: "Rack 1", Value: float64(i)}})
+	}
+
+	readings := recent.Get("server")["Rack 1"]
+	if len(readings) != 3 {
+		t.Fatalf("expected 3 readings, got %d", len(readings))
+	}
+	for i, reading := range readings {
+		if reading.Value != float64(i+2) {
+			t.Errorf("reading %d is %g, want %d", i, reading.Value, i+2)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces secrets in log output.
+const redacted = "[REDACTED]"
+
+// minSubstringSecret is the length below which secrets are only redacted
+// as whole words, so that a short community like "public" does not
+// mangle every word containing it. Secrets shorter than minSecret are not
+// redacted at all.
+const (
+	minSubstringSecret = 8
+	minSecret          = 4
+)
+
+// redaction scrubs the secrets of the active configuration from the log
+// output, API error messages and debug dumps.
+var redaction = &redactor{}
+
+// redactor scrubs the secrets of the configuration from all log lines,
+// including error messages and SNMP traces logged as fields.
+type redactor struct {
+	scrubber atomic.Pointer[scrubber]
+}
+
+// scrubber replaces long secrets anywhere and short secrets as whole words.
+type scrubber struct {
+	replacer *strings.Replacer
+	words    *regexp.Regexp
+}
+
+// Set replaces the secrets to scrub.
+func (r *redactor) Set(secrets []string) {
+	secrets = slices.DeleteFunc(slices.Clone(secrets), func(s string) bool { return len(s) < minSecret })
+	// Scrub longer secrets first in case one contains another.
+	slices.SortFunc(secrets, func(a, b string) int { return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b)) })
+	secrets = slices.Compact(secrets)
+	var (
+		pairs []string
+		words []string
+	)
+	for _, secret := range secrets {
+		if len(secret) >= minSubstringSecret {
+			pairs = append(pairs, secret, redacted)
+			continue
+		}
+		word := regexp.QuoteMeta(secret)
+		if isWordRune(secret[0]) {
+			word = `\b` + word
+		}
+		if isWordRune(secret[len(secret)-1]) {
+			word += `\b`
+		}
+		words = append(words, word)
+	}
+	s := &scrubber{replacer: strings.NewReplacer(pairs...)}
+	if len(words) > 0 {
+		s.words = regexp.MustCompile(strings.Join(words, "|"))
+	}
+	r.scrubber.Store(s)
+}
+
+// isWordRune reports whether the byte matches \w, where \b applies.
+func isWordRune(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// Reload scrubs the secrets of the configuration.
+func (r *redactor) Reload(config config) {
+	r.Set(config.secrets())
+}
+
+// Redact returns the string with all secrets replaced.
+func (r *redactor) Redact(s string) string {
+	scrubber := r.scrubber.Load()
+	if scrubber == nil {
+		return s
+	}
+	s = scrubber.replacer.Replace(s)
+	if scrubber.words != nil {
+		s = scrubber.words.ReplaceAllLiteralString(s, redacted)
+	}
+	return s
+}
+
+// redactValue replaces secrets in the strings of a value decoded from
+// JSON or encoded by a zapcore.MapObjectEncoder.
+func (r *redactor) redactValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.Redact(v)
+	case []any:
+		for i := range v {
+			v[i] = r.redactValue(v[i])
+		}
+	case []map[string]any:
+		for i := range v {
+			r.redactValue(v[i])
+		}
+	case map[string]any:
+		for key := range v {
+			v[key] = r.redactValue(v[key])
+		}
+	}
+	return value
+}
+
+// core wraps the core of a logger, e.g. via zap.WrapCore.
+func (r *redactor) core(core zapcore.Core) zapcore.Core {
+	return redactingCore{Core: core, redactor: r}
+}
+
+// fields returns the fields with secrets replaced in string, error and
+// stringer values, and in the strings of arrays, objects and reflected
+// values.
+func (r *redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			f.String = r.Redact(f.String)
+		case zapcore.ErrorType:
+			if err, ok := f.Interface.(error); ok {
+				f = zap.String(f.Key, r.Redact(err.Error()))
+			}
+		case zapcore.StringerType:
+			if s, ok := f.Interface.(fmt.Stringer); ok {
+				f = zap.String(f.Key, r.Redact(s.String()))
+			}
+		case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+			encoder := zapcore.NewMapObjectEncoder()
+			f.AddTo(encoder)
+			f = zap.Any(f.Key, r.redactValue(encoder.Fields[f.Key]))
+		case zapcore.ReflectType:
+			var value any
+			data, err := json.Marshal(f.Interface)
+			if err == nil {
+				err = json.Unmarshal(data, &value)
+			}
+			if err != nil {
+				f = zap.String(f.Key, r.Redact(fmt.Sprint(f.Interface)))
+				break
+			}
+			f = zap.Any(f.Key, r.redactValue(value))
+		}
+		result[i] = f
+	}
+	return result
+}
+
+// redactingCore passes all entries through the redactor.
+type redactingCore struct {
+	zapcore.Core
+	redactor *redactor
+}
+
+func (c redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactingCore{Core: c.Core.With(c.redactor.fields(fields)), redactor: c.redactor}
+}
+
+func (c redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Redact(entry.Message)
+	return c.Core.Write(entry, c.redactor.fields(fields))
+}
+
+// secrets returns all credentials of the configuration.
+func (c config) secrets() []string {
+	secrets := []string{c.Community, c.AdminToken, c.Webhook.URL}
+	for _, target := range c.Targets {
+		secrets = append(secrets, target.PushToken)
+	}
+	for _, listener := range c.Web.Listeners {
+		if listener.BasicAuth != nil {
+			secrets = append(secrets, listener.BasicAuth.Password)
+		}
+	}
+	for _, route := range c.Web.Routes {
+		secrets = append(secrets, route.BearerToken)
+		if route.BasicAuth != nil {
+			secrets = append(secrets, route.BasicAuth.Password)
+		}
+	}
+	for _, tenant := range c.Tenants {
+		secrets = append(secrets, tenant.Community, tenant.BearerToken)
+		if tenant.BasicAuth != nil {
+			secrets = append(secrets, tenant.BasicAuth.Password)
+		}
+		for _, target := range tenant.Targets {
+			secrets = append(secrets, target.PushToken)
+		}
+	}
+	return secrets
+}
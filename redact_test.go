@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactor(t *testing.T) {
+	redaction := &redactor{}
+	redaction.Reload(config{Community: "s3cret", AdminToken: "s3cret-token"})
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(redaction.core(core)).With(zap.String("community", "s3cret"))
+
+	logger.Info("Walking with s3cret-token", zap.Error(errors.New("rejected community s3cret")))
+	entry := logs.All()[0]
+	if entry.Message != "Walking with [REDACTED]" {
+		t.Errorf("secret not redacted from message %q", entry.Message)
+	}
+	fields := entry.ContextMap()
+	if fields["community"] != redacted || fields["error"] != "rejected community [REDACTED]" {
+		t.Errorf("secret not redacted from fields %v", fields)
+	}
+}
+
+func TestRedactorShortSecrets(t *testing.T) {
+	redaction := &redactor{}
+	redaction.Set([]string{"public", "abc"})
+
+	tests := map[string]string{
+		"community public rejected":   "community [REDACTED] rejected",
+		"publication of the readings": "publication of the readings",
+		"public,public":               "[REDACTED],[REDACTED]",
+		"abc is too short to redact":  "abc is too short to redact",
+	}
+	for s, expected := range tests {
+		if got := redaction.Redact(s); got != expected {
+			t.Errorf("Redact(%q) = %q, want %q", s, got, expected)
+		}
+	}
+}
+
+func TestRedactorStructuredFields(t *testing.T) {
+	redaction := &redactor{}
+	redaction.Set([]string{"s3cret-token"})
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(redaction.core(core))
+
+	logger.Info("Request",
+		zap.Strings("args", []string{"--token", "s3cret-token"}),
+		zap.Any("headers", map[string]string{"Authorization": "Bearer s3cret-token"}),
+	)
+	fields := logs.All()[0].ContextMap()
+	if fmt.Sprint(fields["args"]) != "[--token [REDACTED]]" {
+		t.Errorf("secret not redacted from array %v", fields["args"])
+	}
+	if fmt.Sprint(fields["headers"]) != "map[Authorization:Bearer [REDACTED]]" {
+		t.Errorf("secret not redacted from reflected value %v", fields["headers"])
+	}
+}
+
+func TestWriteErrorRedacted(t *testing.T) {
+	redaction.Set([]string{"s3cret-community"})
+	t.Cleanup(func() { redaction.Set(nil) })
+
+	w := httptest.NewRecorder()
+	writeError(w, http.StatusForbidden, apiError{Code: errorCodeSNMPAuth, Message: "rejected community s3cret-community"})
+	if strings.Contains(w.Body.String(), "s3cret-community") {
+		t.Errorf("secret not redacted from the response %s", w.Body.String())
+	}
+}
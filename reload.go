@@ -0,0 +1,143 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// setupConfig sets the search paths and defaults of the configuration.
+func setupConfig() {
+	viper.SetConfigName("config")
+	viper.SetConfigType("yaml")
+	viper.AddConfigPath("/etc/wut-temperature-exporter/")
+	viper.AddConfigPath(".")
+	viper.SetDefault("config_version", currentConfigVersion)
+	viper.SetDefault("scrape_interval", time.Minute)
+	defaults := wutconfig.DefaultOptions()
+	viper.SetDefault("error_values", defaults.ErrorValues)
+	viper.SetDefault("normalize_unit", defaults.NormalizeUnit)
+	viper.SetDefault("lowercase_labels", defaults.LowercaseLabels)
+	viper.SetDefault("sensor_labels", defaults.SensorLabels)
+	viper.SetDefault("sensor_label_name", defaults.SensorLabelName)
+	viper.SetDefault("sensor_index_base", defaults.SensorIndexBase)
+	viper.SetDefault("device_type", defaults.DeviceType)
+	viper.SetDefault("metric_names", defaults.MetricNames)
+	viper.SetDefault("max_varbinds", defaults.MaxVarbinds)
+	// Below the default scrape_timeout of Prometheus.
+	viper.SetDefault("probe_timeout", 9*time.Second)
+	viper.SetDefault("canary_timeout", 10*time.Second)
+	// Fits into the default grace period of docker stop.
+	viper.SetDefault("drain_timeout", 8*time.Second)
+	viper.SetDefault("self_test", false)
+	viper.SetDefault("fips", false)
+	viper.SetDefault("stale_readings", staleReadingsWithhold)
+	viper.SetDefault("history.retention", 7*24*time.Hour)
+	viper.SetDefault("webhook.failures", 3)
+	viper.SetDefault("ha.lease_duration", 15*time.Second)
+	viper.SetDefault("webhook.timeout", 10*time.Second)
+	viper.SetDefault("tracing.sample_ratio", 1.0)
+	viper.SetDefault("web.read_header_timeout", 10*time.Second)
+	viper.SetDefault("web.read_timeout", 30*time.Second)
+	viper.SetDefault("web.write_timeout", 2*time.Minute)
+	viper.SetDefault("web.idle_timeout", 2*time.Minute)
+	viper.SetDefault("web.http2", true)
+	viper.SetDefault("web.h2c", false)
+	viper.SetDefault("web.compression.enabled", true)
+	viper.SetDefault("web.compression.level", gzip.DefaultCompression)
+}
+
+// loadConfig reads and decodes the configuration file.
+func loadConfig() (config, error) {
+	c := config{}
+	if err := viper.ReadInConfig(); err != nil {
+		return c, err
+	}
+	if err := verifyConfig(); err != nil {
+		return c, err
+	}
+	if err := decryptConfig(); err != nil {
+		return c, err
+	}
+	if err := viper.Unmarshal(&c); err != nil {
+		return c, err
+	}
+	return c, c.validate()
+}
+
+// configStore holds the active configuration and replaces it on reload.
+type configStore struct {
+	logger *zap.Logger
+
+	mu       sync.RWMutex
+	config   config
+	onReload []func(config)
+}
+
+func newConfigStore(config config, logger *zap.Logger) *configStore {
+	configReloadSuccess.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+	return &configStore{config: config, logger: logger}
+}
+
+// Get returns the active configuration.
+func (s *configStore) Get() config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// OnReload registers a function that is called with the new configuration
+// after every successful reload.
+func (s *configStore) OnReload(f func(config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onReload = append(s.onReload, f)
+}
+
+// Reload reads the configuration file again. On failure the active
+// configuration is kept.
+func (s *configStore) Reload() error {
+	c, err := loadConfig()
+	if err != nil {
+		configReloadSuccess.Set(0)
+		s.logger.Error("Error reloading configuration", zap.Error(err))
+		return err
+	}
+
+	s.mu.Lock()
+	c.Simulate = s.config.Simulate
+	c.SNMPDebug = s.config.SNMPDebug
+	c.Shard = s.config.Shard
+	c = c.sharded()
+	s.config = c
+	callbacks := s.onReload
+	s.mu.Unlock()
+
+	for _, f := range callbacks {
+		f(c)
+	}
+	configReloadSuccess.Set(1)
+	configReloadTimestamp.SetToCurrentTime()
+	s.logger.Info("Configuration reloaded", zap.Int("targets", len(c.Targets)))
+	return nil
+}
+
+// reloadHandler triggers a configuration reload on POST /-/reload.
+func (s *configStore) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.Header().Set("Allow", "POST, PUT")
+		writeError(w, http.StatusMethodNotAllowed, apiError{Code: errorCodeMethodNotAllowed, Message: "Only POST or PUT requests allowed"})
+		return
+	}
+	if err := s.Reload(); err != nil {
+		writeError(w, http.StatusInternalServerError, apiError{Code: errorCodeReloadFailed, Message: fmt.Sprintf("Failed to reload config: %s", err)})
+	}
+}
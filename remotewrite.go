@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// sample is a single value of a time series.
+type sample struct {
+	Value     float64
+	Timestamp time.Time
+}
+
+// timeSeries is a labeled series of samples sent via remote write.
+type timeSeries struct {
+	Labels  map[string]string
+	Samples []sample
+}
+
+// encodeWriteRequest encodes the series as a Prometheus remote write
+// WriteRequest protobuf message.
+func encodeWriteRequest(series []timeSeries) []byte {
+	var request []byte
+	for _, ts := range series {
+		var encoded []byte
+
+		// Labels must be sorted by name.
+		names := make([]string, 0, len(ts.Labels))
+		for name := range ts.Labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, ts.Labels[name])
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, label)
+		}
+
+		for _, s := range ts.Samples {
+			var encodedSample []byte
+			encodedSample = protowire.AppendTag(encodedSample, 1, protowire.Fixed64Type)
+			encodedSample = protowire.AppendFixed64(encodedSample, math.Float64bits(s.Value))
+			encodedSample = protowire.AppendTag(encodedSample, 2, protowire.VarintType)
+			encodedSample = protowire.AppendVarint(encodedSample, uint64(s.Timestamp.UnixMilli()))
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendBytes(encoded, encodedSample)
+		}
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, encoded)
+	}
+	return request
+}
+
+// remoteWrite sends the series to a Prometheus remote write endpoint.
+func remoteWrite(client *http.Client, url string, series []timeSeries) error {
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "wut-temperature-exporter/"+version)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write failed with %s: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// deviceLabel tells apart the metrics without sensor label, such as
+// wut_up, of the devices of a room probed as a whole.
+const deviceLabel = "device"
+
+// errNotScraped marks devices of a room without a background scrape yet.
+var errNotScraped = errors.New("target has not been scraped yet")
+
+// device holds the metrics of one of the devices of a room.
+type device struct {
+	name    string
+	metrics []prometheus.Metric
+	err     error
+}
+
+// deviceName returns the name of the target among the devices of its room.
+func deviceName(t wutconfig.Target) string {
+	if t.Device != "" {
+		return t.Device
+	}
+	return t.IP
+}
+
+// roomMetrics returns the merged metrics of all devices of a room. Devices
+// whose scrape failed contribute their up metric, the probe only fails if
+// all of them did.
+func roomMetrics(ctx context.Context, config config, poller *poller, targets []wutconfig.Target, logger *zap.Logger) ([]prometheus.Metric, error) {
+	devices := make([]device, len(targets))
+	if poller != nil {
+		now := time.Now()
+		for i, t := range targets {
+			devices[i].name = deviceName(t)
+			result, ok := poller.Result(t)
+			if !ok {
+				devices[i].err = errNotScraped
+				continue
+			}
+			c := config.collector(t, logger)
+			if result.Err != nil {
+				devices[i].metrics, devices[i].err = c.Timestamped(result), result.Err
+				continue
+			}
+			result, stale := config.applyMaxAge(c, result, now, poller.started)
+			devices[i].metrics = append(append(c.Timestamped(result), stale...), poller.Aggregates(c, t, now)...)
+		}
+	} else {
+		var wg sync.WaitGroup
+		for i, t := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c := config.collector(t, logger)
+				result := c.Scrape(ctx)
+				if result.Err != nil {
+					logger.Error("Error scraping SNMP target", zap.String("ip", t.IP), zap.String("reason", collector.Reason(result.Err)), zap.Error(result.Err))
+				}
+				devices[i] = device{name: deviceName(t), metrics: c.Metrics(result), err: result.Err}
+			}()
+		}
+		wg.Wait()
+	}
+
+	var err error
+	failed := 0
+	for _, d := range devices {
+		if d.err != nil {
+			failed++
+			if err == nil || errors.Is(err, errNotScraped) {
+				err = d.err
+			}
+		}
+	}
+	if failed == len(devices) {
+		return nil, err
+	}
+	return deconflict(devices, config.SensorLabel())
+}
+
+// deconflict labels the metrics of the devices apart. Sensor names found
+// on more than one device are suffixed with "@" and the device name,
+// metrics without sensor label get the device label.
+func deconflict(devices []device, sensorLabel string) ([]prometheus.Metric, error) {
+	written := make([][]*dto.Metric, len(devices))
+	owners := make(map[string]map[string]bool)
+	for i, d := range devices {
+		for _, metric := range d.metrics {
+			var m dto.Metric
+			if err := metric.Write(&m); err != nil {
+				return nil, err
+			}
+			written[i] = append(written[i], &m)
+			for _, label := range m.GetLabel() {
+				if label.GetName() == sensorLabel {
+					if owners[label.GetValue()] == nil {
+						owners[label.GetValue()] = make(map[string]bool)
+					}
+					owners[label.GetValue()][d.name] = true
+				}
+			}
+		}
+	}
+
+	var metrics []prometheus.Metric
+	for i, d := range devices {
+		for j, metric := range d.metrics {
+			m := written[i][j]
+			sensor := slices.IndexFunc(m.GetLabel(), func(label *dto.LabelPair) bool { return label.GetName() == sensorLabel })
+			switch {
+			case sensor < 0:
+				m.Label = append(m.Label, &dto.LabelPair{Name: proto.String(deviceLabel), Value: proto.String(d.name)})
+				slices.SortFunc(m.Label, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+			case len(owners[m.Label[sensor].GetValue()]) > 1:
+				m.Label[sensor].Value = proto.String(m.Label[sensor].GetValue() + "@" + d.name)
+			}
+			metrics = append(metrics, deviceMetric{Metric: metric, written: m})
+		}
+	}
+	return metrics, nil
+}
+
+// deviceMetric is a metric of a device with the labels set by deconflict.
+type deviceMetric struct {
+	prometheus.Metric
+	written *dto.Metric
+}
+
+// Write implements prometheus.Metric.
+func (m deviceMetric) Write(out *dto.Metric) error {
+	proto.Merge(out, m.written)
+	return nil
+}
+
+// serveRoom serves the merged metrics of all devices of the room.
+func serveRoom(w http.ResponseWriter, r *http.Request, config config, poller *poller, targets []wutconfig.Target, registerer prometheus.Registerer, logger *zap.Logger) bool {
+	room := targets[0].Room
+	metrics, err := roomMetrics(r.Context(), config, poller, targets, logger)
+	switch {
+	case poller == nil && deadlineExceeded(r.Context()):
+		logger.Error("Probe timed out", zap.String("target", room))
+		writeError(w, http.StatusGatewayTimeout, apiError{Code: errorCodeSNMPTimeout, Message: "Probe timed out", Target: room, Hint: "check that the devices are reachable via SNMP"})
+		return false
+	case errors.Is(err, errNotScraped):
+		writeError(w, http.StatusServiceUnavailable, apiError{Code: errorCodeNotScraped, Message: "Room has not been scraped yet", Target: room, Hint: "retry after the first background scrape"})
+		return false
+	case err != nil:
+		writeScrapeError(w, room, err)
+		return false
+	}
+	registerer.MustRegister(staticCollector(metrics))
+	return true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+var (
+	roomAvgFamily     = collector.NewFamily("wut_room_temperature_avg", "Average of the latest temperature readings of all WUT sensors in the room", "room")
+	roomMinFamily     = collector.NewFamily("wut_room_temperature_min", "Minimum of the latest temperature readings of all WUT sensors in the room", "room")
+	roomMaxFamily     = collector.NewFamily("wut_room_temperature_max", "Maximum of the latest temperature readings of all WUT sensors in the room", "room")
+	roomSensorsFamily = collector.NewFamily("wut_room_sensors", "Number of WUT sensors with a current temperature reading in the room", "room")
+)
+
+// roomAggregates exports the average, minimum and maximum of the latest
+// temperature readings of all sensors and devices of every room, so that
+// alerting rules need no aggregation even in rooms with many probes. The
+// rooms span several targets, which is why the aggregates are served on
+// /metrics rather than on the probes of the individual targets.
+type roomAggregates struct {
+	store  *configStore
+	poller *poller
+}
+
+// room accumulates the readings of a room.
+type room struct {
+	sum, lowest, highest float64
+	sensors              int
+}
+
+// Collect implements prometheus.Collector. Targets whose last scrape failed
+// and readings older than max_age are left out.
+func (a roomAggregates) Collect(metrics chan<- prometheus.Metric) {
+	config := a.store.Get()
+	now := time.Now()
+	rooms := make(map[string]*room)
+	for _, target := range config.Targets {
+		result, ok := a.poller.Result(target)
+		if !ok || result.Err != nil {
+			continue
+		}
+		name := config.collector(target, zap.NewNop()).RoomLabel()
+		for _, reading := range result.Readings {
+			if !collector.IsTemperature(reading.Unit) || (config.MaxAge > 0 && now.Sub(reading.Timestamp) > config.MaxAge) {
+				continue
+			}
+			r, ok := rooms[name]
+			if !ok {
+				r = &room{lowest: math.Inf(1), highest: math.Inf(-1)}
+				rooms[name] = r
+			}
+			r.sum += reading.Value
+			r.lowest = min(r.lowest, reading.Value)
+			r.highest = max(r.highest, reading.Value)
+			r.sensors++
+		}
+	}
+
+	c := config.collector(wutconfig.Target{}, zap.NewNop())
+	for name, r := range rooms {
+		metrics <- prometheus.MustNewConstMetric(c.Desc(roomAvgFamily), prometheus.GaugeValue, r.sum/float64(r.sensors), name)
+		metrics <- prometheus.MustNewConstMetric(c.Desc(roomMinFamily), prometheus.GaugeValue, r.lowest, name)
+		metrics <- prometheus.MustNewConstMetric(c.Desc(roomMaxFamily), prometheus.GaugeValue, r.highest, name)
+		metrics <- prometheus.MustNewConstMetric(c.Desc(roomSensorsFamily), prometheus.GaugeValue, float64(r.sensors), name)
+	}
+}
+
+// Describe implements prometheus.Collector. The collector is unchecked as
+// the rooms depend on the configuration.
+func (roomAggregates) Describe(chan<- *prometheus.Desc) {}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestRoomAggregates(t *testing.T) {
+	config := config{
+		Targets: []wutconfig.Target{
+			{IP: "10.0.0.1", Room: "Server"},
+			{IP: "10.0.0.2", Room: "server"},
+			{IP: "10.0.0.3", Room: "Lab"},
+			{IP: "10.0.0.4", Room: "Office"},
+		},
+		MaxAge:  10 * time.Minute,
+		Options: wutconfig.DefaultOptions(),
+	}
+	poller := newPoller(t.Context(), config, zap.NewNop())
+	now := time.Now()
+	poller.results["Server"] = collector.Result{Readings: []collector.Reading{
+		{Sensor: "inlet", Value: 20, Timestamp: now},
+		{Sensor: "outlet", Value: 26, Timestamp: now},
+		{Sensor: "humidity", Value: 45, Unit: collector.UnitPercentRH, Timestamp: now},
+	}}
+	poller.results["server"] = collector.Result{Readings: []collector.Reading{
+		{Sensor: "rack", Value: 23, Timestamp: now},
+		{Sensor: "old", Value: 40, Timestamp: now.Add(-time.Hour)},
+	}}
+	poller.results["Lab"] = collector.Result{Readings: []collector.Reading{{Sensor: "bench", Value: 19.5, Timestamp: now}}}
+	poller.results["Office"] = collector.Result{Err: errors.New("timeout")}
+
+	expected := `
+# HELP wut_room_sensors Number of WUT sensors with a current temperature reading in the room
+# TYPE wut_room_sensors gauge
+wut_room_sensors{room="lab"} 1
+wut_room_sensors{room="server"} 3
+# HELP wut_room_temperature_avg Average of the latest temperature readings of all WUT sensors in the room
+# TYPE wut_room_temperature_avg gauge
+wut_room_temperature_avg{room="lab"} 19.5
+wut_room_temperature_avg{room="server"} 23
+# HELP wut_room_temperature_max Maximum of the latest temperature readings of all WUT sensors in the room
+# TYPE wut_room_temperature_max gauge
+wut_room_temperature_max{room="lab"} 19.5
+wut_room_temperature_max{room="server"} 26
+# HELP wut_room_temperature_min Minimum of the latest temperature readings of all WUT sensors in the room
+# TYPE wut_room_temperature_min gauge
+wut_room_temperature_min{room="lab"} 19.5
+wut_room_temperature_min{room="server"} 20
+`
+	aggregates := roomAggregates{store: newConfigStore(config, zap.NewNop()), poller: poller}
+	if err := testutil.CollectAndCompare(aggregates, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// parseExternalURL parses the URL the exporter is reachable at from the
+// outside, e.g. behind a reverse proxy. An empty string yields nil.
+func parseExternalURL(externalURL string) (*url.URL, error) {
+	if externalURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(externalURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --web.external-url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid --web.external-url %q, scheme must be http or https", externalURL)
+	}
+	return u, nil
+}
+
+// routePrefix returns the path prefix all endpoints are served under. It
+// defaults to the path of the external URL, like in Prometheus. The result
+// starts with a slash and has no trailing slash unless it is the root.
+func routePrefix(prefix string, externalURL *url.URL) string {
+	if prefix == "" && externalURL != nil {
+		prefix = externalURL.Path
+	}
+	return "/" + strings.Trim(prefix, "/")
+}
+
+// withRoutePrefix serves the handler under the prefix and redirects
+// requests for the root to it.
+func withRoutePrefix(prefix string, handler http.Handler) http.Handler {
+	if prefix == "/" {
+		return handler
+	}
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := url.URL{Path: prefix + "/", RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	})
+	mux := http.NewServeMux()
+	mux.Handle(prefix+"/", http.StripPrefix(prefix, handler))
+	mux.Handle(prefix, redirect)
+	mux.Handle("/{$}", redirect)
+	return mux
+}
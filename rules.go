@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// ruleFile is a Prometheus rule file.
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// rulesFlags are the flags of the generate-rules command.
+type rulesFlags struct {
+	job     *string
+	pending *time.Duration
+	output  *string
+}
+
+// newRulesFlags defines the flags of the generate-rules command on the flag set.
+func newRulesFlags(flags *pflag.FlagSet) rulesFlags {
+	f := rulesFlags{
+		job:     flags.String("job", "wut", "Prometheus job probing the exporter"),
+		pending: flags.Duration("for", 5*time.Minute, "Time a condition has to hold before the alerts fire"),
+		output:  flags.String("output", "", "File to write the rules to instead of stdout"),
+	}
+	addPublicKeyFlag(flags)
+	return f
+}
+
+// runGenerateRules prints Prometheus alerting rules for the configured
+// targets.
+func runGenerateRules(args []string) int {
+	flags := pflag.NewFlagSet("generate-rules", pflag.ContinueOnError)
+	f := newRulesFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	setupConfig()
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "No valid configuration found: %v\n", err)
+		return 1
+	}
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config.alertRules(*f.job, *f.pending)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *f.output == "" {
+		os.Stdout.Write(data.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(*f.output, data.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// alertRules returns rules alerting on failing probes of the job, targets
+// not scraped successfully for three intervals, disconnected sensors and
+// temperatures above the thresholds of the targets.
+func (c config) alertRules(job string, pending time.Duration) ruleFile {
+	rules := []alertRule{{
+		Alert:       "WUTProbeFailed",
+		Expr:        fmt.Sprintf("up{job=%q} == 0", job),
+		For:         model.Duration(pending).String(),
+		Labels:      map[string]string{"severity": "critical"},
+		Annotations: map[string]string{"summary": "Probe of WUT device {{ $labels.instance }} failed"},
+	}, {
+		Alert:       "WUTSensorDisconnected",
+		Expr:        "wut_sensor_connected == 0",
+		For:         model.Duration(pending).String(),
+		Labels:      map[string]string{"severity": "warning"},
+		Annotations: map[string]string{"summary": fmt.Sprintf("Sensor {{ $labels.%s }} in {{ $labels.room }} is disconnected", c.SensorLabel())},
+	}}
+
+	temperature := c.temperatureMetric()
+	for _, target := range c.Targets {
+		interval := target.Interval(c.ScrapeInterval)
+		rules = append(rules, alertRule{
+			Alert:       "WUTTargetDown",
+			Expr:        fmt.Sprintf("time() - wut_last_scrape_success_timestamp_seconds{target=%q} > %g", target.Name(), 3*interval.Seconds()),
+			Labels:      map[string]string{"severity": "critical"},
+			Annotations: map[string]string{"summary": fmt.Sprintf("WUT device %s has not been scraped successfully for three intervals", target.Name())},
+		})
+
+		room := c.collector(target, zap.NewNop()).RoomLabel()
+		thresholds := target.AlertThresholds(c.AlertThresholds)
+		for _, limit := range []struct {
+			severity string
+			value    *float64
+		}{{"warning", thresholds.Warning}, {"critical", thresholds.Critical}} {
+			if limit.value == nil {
+				continue
+			}
+			value := strconv.FormatFloat(*limit.value, 'g', -1, 64)
+			rules = append(rules, alertRule{
+				Alert:  "WUTTemperatureHigh",
+				Expr:   fmt.Sprintf("%s{room=%q} > %s", temperature, room, value),
+				For:    model.Duration(pending).String(),
+				Labels: map[string]string{"severity": limit.severity},
+				Annotations: map[string]string{
+					"summary":     fmt.Sprintf("Temperature in %s above %s", target.Name(), value),
+					"description": fmt.Sprintf("Sensor {{ $labels.%s }} reads {{ $value }}.", c.SensorLabel()),
+				},
+			})
+		}
+	}
+	return ruleFile{Groups: []ruleGroup{{Name: "wut-temperature-exporter", Rules: rules}}}
+}
+
+// temperatureMetric returns the name of the temperature metric exported
+// with the options, preferring the legacy name if both are exported.
+func (c config) temperatureMetric() string {
+	target := wutconfig.Target{IP: "192.0.2.1"}
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(staticCollector(c.collector(target, zap.NewNop()).SimulatedMetrics(time.Now())))
+	families, _ := registry.Gather()
+	for _, family := range families {
+		if strings.HasPrefix(family.GetName(), "wut_temperature") {
+			return family.GetName()
+		}
+	}
+	return "wut_temperature"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestAlertRulesThresholds(t *testing.T) {
+	warning, critical := 27.0, 32.5
+	config := config{Options: wutconfig.DefaultOptions(), ScrapeInterval: time.Minute}
+	config.AlertThresholds.Warning = &warning
+	config.Targets = []wutconfig.Target{
+		{IP: "10.0.0.1", Room: "Server", Thresholds: &wutconfig.Thresholds{Critical: &critical}},
+		{IP: "10.0.0.2", Room: "Lab", ScrapeInterval: 10 * time.Second},
+	}
+
+	var exprs []string
+	for _, rule := range config.alertRules("wut", 5*time.Minute).Groups[0].Rules {
+		exprs = append(exprs, rule.Expr)
+	}
+	expected := []string{
+		`up{job="wut"} == 0`,
+		`wut_sensor_connected == 0`,
+		`time() - wut_last_scrape_success_timestamp_seconds{target="Server"} > 180`,
+		`wut_temperature{room="server"} > 27`,
+		`wut_temperature{room="server"} > 32.5`,
+		`time() - wut_last_scrape_success_timestamp_seconds{target="Lab"} > 30`,
+		`wut_temperature{room="lab"} > 27`,
+	}
+	if len(exprs) != len(expected) {
+		t.Fatalf("expected %d rules, got %q", len(expected), exprs)
+	}
+	for i := range expected {
+		if exprs[i] != expected[i] {
+			t.Errorf("rule %d: expected %s, got %s", i, expected[i], exprs[i])
+		}
+	}
+}
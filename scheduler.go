@@ -0,0 +1,85 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// scheduledTarget is the next background scrape of a target.
+type scheduledTarget struct {
+	target   wutconfig.Target
+	interval time.Duration
+	due      time.Time
+}
+
+// advance moves the due time past now in steps of the interval. Like a
+// ticker it drops scrapes missed while the scheduler was delayed instead of
+// catching up, keeping the phase of the target.
+func (s *scheduledTarget) advance(now time.Time) {
+	missed := now.Sub(s.due) / s.interval
+	s.due = s.due.Add((missed + 1) * s.interval)
+}
+
+// scheduleHeap orders the targets by their next due time.
+type scheduleHeap []*scheduledTarget
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].due.Before(h[j].due) }
+func (h scheduleHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *scheduleHeap) Push(x any)        { *h = append(*h, x.(*scheduledTarget)) }
+func (h *scheduleHeap) Pop() any {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// schedule queues the scrapes of all targets as they become due, starting
+// after their jitter offset, until the context is cancelled. A single timer
+// waits for the earliest target, so that the cost per target is one heap
+// entry regardless of the number of targets.
+func (p *poller) schedule(ctx context.Context, config config, queue *scrapeQueue) {
+	now := time.Now()
+	targets := make(scheduleHeap, 0, len(config.Targets))
+	for _, target := range config.Targets {
+		interval := target.Interval(config.ScrapeInterval)
+		offset := jitterOffset(target, interval, config.ScrapeJitter)
+		p.logger.Debug("Scheduling background scrapes", zap.String("target", target.Name()), zap.Duration("interval", interval), zap.Duration("offset", offset), zap.String("priority", target.Priority))
+		targets = append(targets, &scheduledTarget{target: target, interval: interval, due: now.Add(offset)})
+	}
+	heap.Init(&targets)
+	schedulerTargets.Set(float64(len(targets)))
+	p.logger.Info("Starting background scrapes", zap.Int("targets", len(targets)), zap.Int("workers", config.workers()))
+	if len(targets) == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for !targets[0].due.After(now) {
+			next := targets[0]
+			schedulerLag.Observe(now.Sub(next.due).Seconds())
+			schedulerDispatched.Inc()
+			if !queue.push(next.target) {
+				p.logQueueSkip(next.target)
+			}
+			next.advance(now)
+			heap.Fix(&targets, 0)
+		}
+		timer.Reset(time.Until(targets[0].due))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// selfTest probes every configured target once and logs a summary, so that
+// wrong communities or unroutable devices are noticed right after a deploy
+// rather than on the first alert. The results are exported as
+// wut_self_test_success and wut_self_test_sensors. Failures are not fatal.
+func selfTest(ctx context.Context, config config, logger *zap.Logger) {
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		unreachable []string
+	)
+	for _, target := range config.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, config.ProbeTimeout)
+			defer cancel()
+
+			c := config.collector(target, logger)
+			result := c.Scrape(ctx)
+			name := target.Name()
+			if result.Err != nil {
+				selfTestSuccess.WithLabelValues(name).Set(0)
+				selfTestSensors.WithLabelValues(name).Set(0)
+				logger.Error("Self-test failed", zap.String("target", name), zap.String("ip", target.IP), zap.String("reason", collector.Reason(result.Err)), zap.Error(result.Err))
+				mu.Lock()
+				unreachable = append(unreachable, name)
+				mu.Unlock()
+				return
+			}
+			selfTestSuccess.WithLabelValues(name).Set(1)
+			selfTestSensors.WithLabelValues(name).Set(float64(len(result.Readings)))
+
+			firmware, err := c.Firmware(ctx)
+			if err != nil {
+				logger.Warn("Error querying firmware", zap.String("target", name), zap.Error(err))
+			}
+			logger.Info("Self-test passed", zap.String("target", name), zap.String("ip", target.IP), zap.Int("sensors", len(result.Readings)), zap.String("firmware", firmware))
+		}()
+	}
+	wg.Wait()
+
+	logger.Info("Self-test finished",
+		zap.Int("targets", len(config.Targets)),
+		zap.Int("reachable", len(config.Targets)-len(unreachable)),
+		zap.Strings("unreachable", unreachable),
+	)
+}
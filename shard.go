@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// shard selects the targets handled by one of several instances sharing
+// the same configuration.
+type shard struct {
+	Index int
+	Count int
+}
+
+// parseShard parses a shard in the form "N/M" with N counted from 0. An
+// empty value selects all targets.
+func parseShard(value string) (shard, error) {
+	if value == "" {
+		return shard{Index: 0, Count: 1}, nil
+	}
+	index, count, ok := strings.Cut(value, "/")
+	n, err1 := strconv.Atoi(index)
+	m, err2 := strconv.Atoi(count)
+	if !ok || err1 != nil || err2 != nil || m < 1 || n < 0 || n >= m {
+		return shard{}, fmt.Errorf("invalid shard %q, must be N/M with 0 <= N < M", value)
+	}
+	return shard{Index: n, Count: m}, nil
+}
+
+// owns reports whether the target belongs to the shard, by hashing its
+// address like the hashmod relabeling of Prometheus: the shard of a target
+// matches a hashmod with modulus M of a label holding its ip. MD5 is no
+// cryptographic use here and available in FIPS 140-3 mode, though not
+// with GODEBUG=fips140=only.
+func (s shard) owns(target wutconfig.Target) bool {
+	if s.Count <= 1 {
+		return true
+	}
+	sum := md5.Sum([]byte(target.IP))
+	return binary.BigEndian.Uint64(sum[8:])%uint64(s.Count) == uint64(s.Index)
+}
+
+// sharded returns the configuration reduced to the targets of its shard.
+func (c config) sharded() config {
+	if c.Shard.Count <= 1 {
+		return c
+	}
+	targets := make([]wutconfig.Target, 0, len(c.Targets)/c.Shard.Count+1)
+	for _, target := range c.Targets {
+		if c.Shard.owns(target) {
+			targets = append(targets, target)
+		}
+	}
+	c.Targets = targets
+	return c
+}
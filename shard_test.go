@@ -0,0 +1,17 @@
+package main
+
+import (
+	"testing"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestShardHashmod(t *testing.T) {
+	// The shards of Prometheus' hashmod relabeling with modulus 3.
+	tests := map[string]int{"192.0.2.1": 2, "192.0.2.2": 0, "192.0.2.3": 0, "10.0.0.7": 2}
+	for ip, index := range tests {
+		if !(shard{Index: index, Count: 3}).owns(wutconfig.Target{IP: ip}) {
+			t.Errorf("expected %s in shard %d/3", ip, index)
+		}
+	}
+}
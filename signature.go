@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/blake2b"
+)
+
+// configPublicKey is the file of the minisign public key the configuration
+// file has to be signed with, set by --config.public-key. Signatures are
+// not checked if it is empty.
+var configPublicKey string
+
+// addPublicKeyFlag defines --config.public-key on the flag set of a command
+// that loads the configuration file.
+func addPublicKeyFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&configPublicKey, "config.public-key", "", "Minisign public key file the configuration file has to be signed with in <config file>.minisig")
+}
+
+// minisignPublicKey is an Ed25519 key in the minisign format.
+type minisignPublicKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// readMinisignPublicKey reads a public key file created by "minisign -G".
+func readMinisignPublicKey(path string) (minisignPublicKey, error) {
+	lines, err := readMinisignFile(path, 1)
+	if err != nil {
+		return minisignPublicKey{}, err
+	}
+	data, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(data) != 2+8+ed25519.PublicKeySize || string(data[:2]) != "Ed" {
+		return minisignPublicKey{}, fmt.Errorf("invalid minisign public key in %s", path)
+	}
+	k := minisignPublicKey{key: ed25519.PublicKey(data[10:])}
+	copy(k.id[:], data[2:10])
+	return k, nil
+}
+
+// verify checks a signature created by "minisign -S" of the data. Both the
+// legacy and the prehashed signatures are accepted, but only the legacy
+// ones with fips, as the prehashed ones use BLAKE2b, which is not
+// FIPS-approved.
+func (k minisignPublicKey) verify(data []byte, signatureFile string, fips bool) error {
+	lines, err := readMinisignFile(signatureFile, 3)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(signature) != 2+8+ed25519.SignatureSize {
+		return errors.New("invalid minisign signature")
+	}
+	if !bytes.Equal(signature[2:10], k.id[:]) {
+		return fmt.Errorf("signature created by key %X, expected %X", signature[2:10], k.id)
+	}
+	switch string(signature[:2]) {
+	case "ED":
+		if fips {
+			return errors.New("fips forbids prehashed minisign signatures, as BLAKE2b is not FIPS-approved, sign with \"minisign -S -l\" instead")
+		}
+		digest := blake2b.Sum512(data)
+		data = digest[:]
+	case "Ed":
+	default:
+		return errors.New("unsupported minisign signature algorithm")
+	}
+	if !ed25519.Verify(k.key, data, signature[10:]) {
+		return errors.New("invalid signature")
+	}
+
+	comment, ok := strings.CutPrefix(lines[1], "trusted comment: ")
+	if !ok {
+		return errors.New("invalid minisign signature, missing trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || !ed25519.Verify(k.key, append(signature[10:len(signature):len(signature)], comment...), global) {
+		return errors.New("invalid signature of the trusted comment")
+	}
+	return nil
+}
+
+// readMinisignFile returns the lines of a minisign file following the
+// untrusted comment.
+func readMinisignFile(path string, lines int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), "untrusted comment: ") {
+		return nil, fmt.Errorf("invalid minisign file %s", path)
+	}
+	var result []string
+	for len(result) < lines && scanner.Scan() {
+		result = append(result, strings.TrimSpace(scanner.Text()))
+	}
+	if len(result) < lines {
+		return nil, fmt.Errorf("invalid minisign file %s", path)
+	}
+	return result, scanner.Err()
+}
+
+// verifyConfig checks the signature in <config file>.minisig of the
+// configuration file read by viper and parses the verified content again,
+// so that changes between reading and verifying are not applied.
+func verifyConfig() error {
+	if configPublicKey == "" {
+		return nil
+	}
+	key, err := readMinisignPublicKey(configPublicKey)
+	if err != nil {
+		return fmt.Errorf("error reading public key: %w", err)
+	}
+	file := viper.ConfigFileUsed()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	if err := key.verify(data, file+".minisig", viper.GetBool("fips")); err != nil {
+		return fmt.Errorf("error verifying signature of %s: %w", file, err)
+	}
+	return viper.ReadConfig(bytes.NewReader(data))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/blake2b"
+)
+
+// writeMinisign writes a public key and a prehashed signature of the file
+// in the minisign format.
+func writeMinisign(t *testing.T, dir, file string) string {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	keyFile := filepath.Join(dir, "minisign.pub")
+	key := append(append([]byte("Ed"), id...), public...)
+	os.WriteFile(keyFile, []byte("untrusted comment: minisign public key\n"+base64.StdEncoding.EncodeToString(key)+"\n"), 0o600)
+
+	data, _ := os.ReadFile(file)
+	digest := blake2b.Sum512(data)
+	signature := append(append([]byte("ED"), id...), ed25519.Sign(private, digest[:])...)
+	comment := "timestamp:1700000000"
+	global := ed25519.Sign(private, append(signature[10:len(signature):len(signature)], comment...))
+	os.WriteFile(file+".minisig", []byte("untrusted comment: signature\n"+base64.StdEncoding.EncodeToString(signature)+
+		"\ntrusted comment: "+comment+"\n"+base64.StdEncoding.EncodeToString(global)+"\n"), 0o600)
+	return keyFile
+}
+
+func TestLoadConfigSignature(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	os.WriteFile(file, []byte("community: signed\ntargets: []\n"), 0o600)
+	configPublicKey = writeMinisign(t, dir, file)
+	t.Cleanup(func() { configPublicKey = "" })
+	setupConfig()
+	viper.SetConfigFile(file)
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Community != "signed" {
+		t.Errorf("expected community signed, got %q", config.Community)
+	}
+
+	// Prehashed signatures use BLAKE2b, which fips forbids.
+	key, err := readMinisignPublicKey(configPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(file)
+	if err := key.verify(data, file+".minisig", true); err == nil {
+		t.Error("accepted prehashed signature with fips")
+	}
+
+	os.WriteFile(file, []byte("community: tampered\ntargets: []\n"), 0o600)
+	if _, err := loadConfig(); err == nil {
+		t.Error("loaded tampered configuration")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+var smoothedFamily = collector.NewFamily(
+	"wut_temperature_smoothed",
+	"Exponentially weighted moving average of the temperature readings from WUT sensor",
+	"room", "sensor",
+)
+
+// smoothing exports the exponentially weighted moving average of the
+// readings of every sensor of targets with a smoothing alpha, taming noisy
+// probes while the raw readings are still exported as is.
+type smoothing struct {
+	store *configStore
+
+	mu      sync.Mutex
+	sensors map[string]map[string]float64
+}
+
+func newSmoothing(store *configStore) *smoothing {
+	return &smoothing{store: store, sensors: make(map[string]map[string]float64)}
+}
+
+// Record folds the readings of the target into the averages.
+func (s *smoothing) Record(target string, readings []collector.Reading) {
+	config := s.store.Get()
+	t, ok := config.findTarget(target)
+	if !ok {
+		return
+	}
+	alpha := t.Smoothing(config.SmoothingAlpha)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if alpha <= 0 {
+		delete(s.sensors, target)
+		return
+	}
+	sensors, ok := s.sensors[target]
+	if !ok {
+		sensors = make(map[string]float64)
+		s.sensors[target] = sensors
+	}
+	for _, reading := range readings {
+		average, ok := sensors[reading.Sensor]
+		if !ok {
+			average = reading.Value
+		}
+		sensors[reading.Sensor] = alpha*reading.Value + (1-alpha)*average
+	}
+}
+
+// Metrics returns the averages of all sensors of the target.
+func (s *smoothing) Metrics(c collector.Collector, target string, now time.Time) []prometheus.Metric {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var metrics []prometheus.Metric
+	for sensor, average := range s.sensors[target] {
+		metrics = append(metrics, prometheus.MustNewConstMetric(c.Desc(smoothedFamily), prometheus.GaugeValue, average, c.RoomLabel(), sensor))
+	}
+	return metrics
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/spf13/viper"
+)
+
+// sopsValue matches a value encrypted by SOPS.
+var sopsValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:([^,]*),iv:([^,]+),tag:([^,]+),type:([^,\]]+)\]$`)
+
+// sopsMetadata is the part of the metadata SOPS stores under the "sops" key
+// that is needed to decrypt the values with an age key.
+type sopsMetadata struct {
+	Age []struct {
+		Recipient string `mapstructure:"recipient"`
+		Enc       string `mapstructure:"enc"`
+	} `mapstructure:"age"`
+}
+
+// decryptConfig decrypts the values of the configuration file encrypted
+// with SOPS, e.g. by "sops encrypt --age <recipient> --encrypted-regex
+// '^(community|password|.*token)$'". The age identities are read from
+// SOPS_AGE_KEY or the file named by SOPS_AGE_KEY_FILE, like sops does.
+// Files without SOPS metadata are left unchanged. The message
+// authentication code of the file is not verified.
+func decryptConfig() error {
+	if !viper.IsSet("sops") {
+		return nil
+	}
+	if viper.GetBool("fips") {
+		return errors.New("fips forbids SOPS encryption, as age uses algorithms that are not FIPS-approved")
+	}
+	var metadata sopsMetadata
+	if err := viper.UnmarshalKey("sops", &metadata); err != nil {
+		return fmt.Errorf("invalid sops metadata: %w", err)
+	}
+	if len(metadata.Age) == 0 {
+		return errors.New("sops metadata lists no age recipients, other key types are not supported")
+	}
+
+	identities, err := sopsIdentities()
+	if err != nil {
+		return err
+	}
+	var key []byte
+	for _, recipient := range metadata.Age {
+		key, err = decryptDataKey(recipient.Enc, identities)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("decrypting sops data key: %w", err)
+	}
+
+	settings := viper.AllSettings()
+	delete(settings, "sops")
+	decrypted, err := decryptTree(settings, nil, key)
+	if err != nil {
+		return err
+	}
+	return viper.MergeConfigMap(decrypted.(map[string]any))
+}
+
+// sopsIdentities returns the age identities to decrypt the data key with.
+func sopsIdentities() ([]age.Identity, error) {
+	var keys io.Reader
+	if key := os.Getenv("SOPS_AGE_KEY"); key != "" {
+		keys = strings.NewReader(key)
+	} else if path := os.Getenv("SOPS_AGE_KEY_FILE"); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading age key: %w", err)
+		}
+		defer f.Close()
+		keys = f
+	} else {
+		return nil, errors.New("the configuration is encrypted, set SOPS_AGE_KEY or SOPS_AGE_KEY_FILE")
+	}
+	identities, err := age.ParseIdentities(keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid age key: %w", err)
+	}
+	return identities, nil
+}
+
+// decryptDataKey decrypts the armored data key of an age recipient.
+func decryptDataKey(enc string, identities []age.Identity) ([]byte, error) {
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(enc)), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// decryptTree decrypts all encrypted values below the node. SOPS
+// authenticates every value with the path of keys leading to it, which does
+// not include list indices.
+func decryptTree(node any, path []string, key []byte) (any, error) {
+	switch node := node.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(node))
+		for name, value := range node {
+			decrypted, err := decryptTree(value, append(path[:len(path):len(path)], name), key)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = decrypted
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(node))
+		for i, value := range node {
+			decrypted, err := decryptTree(value, path, key)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = decrypted
+		}
+		return result, nil
+	case string:
+		if !sopsValue.MatchString(node) {
+			return node, nil
+		}
+		value, err := decryptValue(node, strings.Join(path, ":")+":", key)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting %s: %w", strings.Join(path, "."), err)
+		}
+		return value, nil
+	}
+	return node, nil
+}
+
+// decryptValue decrypts a single value encrypted by SOPS.
+func decryptValue(value, additionalData string, key []byte) (any, error) {
+	match := sopsValue.FindStringSubmatch(value)
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(match[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, iv, append(data, tag...), []byte(additionalData))
+	if err != nil {
+		return nil, err
+	}
+
+	switch match[4] {
+	case "str", "bytes":
+		return string(plaintext), nil
+	case "int":
+		return strconv.Atoi(string(plaintext))
+	case "float":
+		return strconv.ParseFloat(string(plaintext), 64)
+	case "bool":
+		return strconv.ParseBool(string(plaintext))
+	}
+	return nil, fmt.Errorf("unsupported type %s", match[4])
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadConfigSOPS(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	t.Setenv("SOPS_AGE_KEY_FILE", "testdata/sops/age.key")
+	setupConfig()
+	viper.SetConfigFile("testdata/sops/config.yaml")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Community != "s3cret" || config.AdminToken != "admin-token" || config.Targets[0].PushToken != "push-token" {
+		t.Errorf("secrets not decrypted: %q %q %q", config.Community, config.AdminToken, config.Targets[0].PushToken)
+	}
+
+	t.Setenv("SOPS_AGE_KEY_FILE", "")
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an error without age key")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// Supported modes of serving cached readings older than max_age.
+const (
+	// staleReadingsWithhold omits stale readings from the response.
+	staleReadingsWithhold = "withhold"
+	// staleReadingsMark serves stale readings and flags them via
+	// wut_data_stale.
+	staleReadingsMark = "mark"
+)
+
+var staleFamily = collector.NewFamily(
+	"wut_data_stale",
+	"Whether the cached reading of the WUT sensor is older than max_age or was restored after a restart",
+	"room", "sensor",
+)
+
+// applyMaxAge handles the readings of a cached result older than max_age
+// according to stale_readings and returns wut_data_stale of every sensor.
+// Readings taken before started were restored from the state file and are
+// always marked stale. Results are returned unchanged if no max_age is
+// configured and no reading was restored.
+func (c config) applyMaxAge(col collector.Collector, result collector.Result, now, started time.Time) (collector.Result, []prometheus.Metric) {
+	if c.MaxAge <= 0 && !result.Timestamp.Before(started) {
+		return result, nil
+	}
+	readings := make([]collector.Reading, 0, len(result.Readings))
+	metrics := make([]prometheus.Metric, 0, len(result.Readings))
+	for _, reading := range result.Readings {
+		expired := c.MaxAge > 0 && now.Sub(reading.Timestamp) > c.MaxAge
+		value := 0.0
+		if expired || reading.Timestamp.Before(started) {
+			value = 1
+		}
+		metrics = append(metrics, prometheus.MustNewConstMetric(col.Desc(staleFamily), prometheus.GaugeValue, value, col.RoomLabel(), reading.Sensor))
+		if !expired || c.StaleReadings == staleReadingsMark {
+			readings = append(readings, reading)
+		}
+	}
+	result.Readings = readings
+	return result, metrics
+}
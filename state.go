@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// stateReading is a reading in the state file.
+type stateReading struct {
+	Sensor    string    `json:"sensor"`
+	Value     float64   `json:"value"`
+	Unit      int       `json:"unit"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Save writes the last readings of all targets to the state file. The file
+// is replaced atomically so that a crash never leaves a truncated state.
+func (p *poller) Save(path string) error {
+	p.mu.RLock()
+	state := make(map[string][]stateReading, len(p.results))
+	for name, result := range p.results {
+		for _, reading := range result.Readings {
+			state[name] = append(state[name], stateReading(reading))
+		}
+	}
+	p.mu.RUnlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the file with the data via a temporary file in
+// the same directory.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Restore loads the readings saved by Save as results of the configured
+// targets. Restored readings keep their timestamps and are exported as
+// stale until the target is scraped again. A missing file is not an error.
+func (p *poller) Restore(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state map[string][]stateReading
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, readings := range state {
+		target, ok := p.config.findTarget(name)
+		if !ok {
+			continue
+		}
+		result := collector.Result{}
+		for _, reading := range readings {
+			result.Readings = append(result.Readings, collector.Reading(reading))
+			if reading.Timestamp.After(result.Timestamp) {
+				result.Timestamp = reading.Timestamp
+			}
+		}
+		p.results[target.Name()] = result
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// syslogHeader matches the header of RFC 3164 and RFC 5424 messages and
+// captures the remaining message.
+var syslogHeader = regexp.MustCompile(`^<(\d{1,3})>(?:1 \S+ \S+ \S+ \S+ \S+ (?:-|\[.*?\]) ?|[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d \S+ )?(.*)$`)
+
+// syslogAlarm matches the alarm messages of the devices, e.g.
+// "Alarm 2 set: Temperature Sensor 1 > 30.0".
+var syslogAlarm = regexp.MustCompile(`(?i)alarm\s*(\d+)\D*?\b(set|on|active|cleared|clear|off|reset)\b`)
+
+// syslogReceiver parses the alarm messages the devices send via syslog and
+// exports them as metrics, for sites where SNMP traps are blocked.
+type syslogReceiver struct {
+	store  *configStore
+	logger *zap.Logger
+	conn   net.PacketConn
+}
+
+// listenSyslog starts listening for syslog messages on the given UDP
+// address.
+func listenSyslog(address string, store *configStore, logger *zap.Logger) (*syslogReceiver, error) {
+	conn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Starting syslog listener", zap.String("address", address))
+	return &syslogReceiver{store: store, logger: logger, conn: conn}, nil
+}
+
+// Serve handles received messages until Close is called.
+func (r *syslogReceiver) Serve() {
+	buf := make([]byte, 8192)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				r.logger.Error("Error receiving syslog message", zap.Error(err))
+			}
+			return
+		}
+		source, _, _ := net.SplitHostPort(addr.String())
+		r.handle(source, string(buf[:n]))
+	}
+}
+
+// Close stops the syslog listener.
+func (r *syslogReceiver) Close() {
+	r.conn.Close()
+}
+
+func (r *syslogReceiver) handle(source string, message string) {
+	config := r.store.Get()
+	target, ok := config.findTarget(source)
+	if !ok {
+		syslogDropped.WithLabelValues("unknown_target").Inc()
+		r.logger.Debug("Dropping syslog message from unknown target", zap.String("ip", source))
+		return
+	}
+
+	match := syslogHeader.FindStringSubmatch(strings.TrimSpace(message))
+	if match == nil {
+		syslogDropped.WithLabelValues("malformed").Inc()
+		return
+	}
+	text := match[2]
+	room := config.collector(target, r.logger).RoomLabel()
+	syslogMessages.WithLabelValues(room).Inc()
+
+	alarm := syslogAlarm.FindStringSubmatch(text)
+	if alarm == nil {
+		r.logger.Debug("Received syslog message", zap.String("target", target.Name()), zap.String("message", text))
+		return
+	}
+	active := 1.0
+	switch strings.ToLower(alarm[2]) {
+	case "cleared", "clear", "off", "reset":
+		active = 0
+	}
+	syslogAlarmEvents.WithLabelValues(room, alarm[1]).Inc()
+	syslogAlarmActive.WithLabelValues(room, alarm[1]).Set(active)
+	r.logger.Info("Received syslog alarm", zap.String("target", target.Name()), zap.String("alarm", alarm[1]), zap.Bool("active", active == 1), zap.String("message", text))
+}
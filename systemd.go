@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// sdNotify sends a state notification to the systemd service manager. It is
+// a no-op if the exporter is not run as a notify service.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if socket[0] == '@' {
+		// Abstract namespace socket.
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns the interval configured via WatchdogSec= in the
+// service unit, or zero if the watchdog is not enabled for this process.
+func watchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// watchdog keeps the systemd watchdog alive as long as healthy reports no
+// error, so that a hung exporter is restarted by the service manager. It
+// returns when the context is cancelled.
+func watchdog(ctx context.Context, healthy func() error, logger *zap.Logger) {
+	interval := watchdogInterval()
+	if interval == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := healthy(); err != nil {
+			logger.Warn("Health check failed, skipping watchdog notification", zap.Error(err))
+			continue
+		}
+		if err := sdNotify("WATCHDOG=1"); err != nil {
+			logger.Warn("Error notifying systemd watchdog", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var targetInfo = promauto.With(selfRegistry).NewGaugeVec(prometheus.GaugeOpts{
+	Name: "wut_target_info",
+	Help: "A metric with a constant '1' value for every configured target, labeled by room, address, tenant group and collection module (snmp or simulation).",
+}, []string{"room", "address", "group", "module"})
+
+// updateTargetInfo exports wut_target_info for the targets of the
+// configuration, whether they were scraped or not, so that dashboards can
+// compare the configured targets with those reporting.
+func updateTargetInfo(config config) {
+	targetInfo.Reset()
+	module := "snmp"
+	if config.Simulate {
+		module = "simulation"
+	}
+	for _, target := range config.Targets {
+		targetInfo.WithLabelValues(config.collector(target, zap.NewNop()).RoomLabel(), target.IP, "", module).Set(1)
+	}
+	for _, tenant := range config.Tenants {
+		tenantConfig := config.tenant(tenant)
+		for _, target := range tenant.Targets {
+			targetInfo.WithLabelValues(tenantConfig.collector(target, zap.NewNop()).RoomLabel(), target.IP, tenant.Name, module).Set(1)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestUpdateTargetInfo(t *testing.T) {
+	t.Cleanup(targetInfo.Reset)
+	updateTargetInfo(config{
+		Targets: []wutconfig.Target{{IP: "10.0.0.1", Room: "Server"}},
+		Tenants: []TenantConfig{{Name: "facility", Targets: []wutconfig.Target{{IP: "10.0.1.1", Room: "plant"}}}},
+		Options: wutconfig.DefaultOptions(),
+	})
+
+	expected := `
+# HELP wut_target_info A metric with a constant '1' value for every configured target, labeled by room, address, tenant group and collection module (snmp or simulation).
+# TYPE wut_target_info gauge
+wut_target_info{address="10.0.0.1",group="",module="snmp",room="server"} 1
+wut_target_info{address="10.0.1.1",group="facility",module="snmp",room="plant"} 1
+`
+	if err := testutil.CollectAndCompare(targetInfo, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// TenantConfig configures a named group of targets probed on
+// /probe/<name> with its own credentials and labels.
+type TenantConfig struct {
+	Name string `mapstructure:"name"`
+	// Community is the SNMP community of the targets of the tenant,
+	// defaults to the global community.
+	Community string             `mapstructure:"community"`
+	Targets   []wutconfig.Target `mapstructure:"targets"`
+	// Labels are added to all metrics of the tenant.
+	Labels map[string]string `mapstructure:"labels"`
+	// BasicAuth requires HTTP basic authentication on the probes of the
+	// tenant.
+	BasicAuth *BasicAuth `mapstructure:"basic_auth"`
+	// BearerToken requires the token as bearer token on the probes of the
+	// tenant, accepted as an alternative to BasicAuth if both are set.
+	BearerToken string `mapstructure:"bearer_token"`
+}
+
+// reservedLabels returns the labels of the exported metrics that cannot be
+// injected by tenants: those of all metric families and the device label of
+// rooms probed as a whole.
+func reservedLabels() []string {
+	return append(collector.FamilyLabels(), deviceLabel)
+}
+
+// validate checks that the tenant is named and has complete
+// authentication settings, and that its labels do not collide with those of
+// the exported metrics including the sensor label.
+func (t TenantConfig) validate(sensorLabel string) error {
+	if t.Name == "" || strings.Contains(t.Name, "/") {
+		return fmt.Errorf("invalid tenant name %q", t.Name)
+	}
+	if t.BasicAuth != nil && (t.BasicAuth.Username == "" || t.BasicAuth.Password == "") {
+		return fmt.Errorf("basic_auth of tenant %s requires username and password", t.Name)
+	}
+	for _, target := range t.Targets {
+		if err := target.Validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+	}
+	for name := range t.Labels {
+		if !model.LabelName(name).IsValidLegacy() || slices.Contains(reservedLabels(), name) || name == sensorLabel {
+			return fmt.Errorf("invalid label name %q of tenant %s", name, t.Name)
+		}
+	}
+	return nil
+}
+
+// authorized reports whether the request carries the credentials of the
+// tenant. Basic auth and bearer token are alternatives, as both are sent in
+// the Authorization header. Requests to tenants without credentials are
+// always authorized.
+func (t TenantConfig) authorized(r *http.Request) bool {
+	if t.BasicAuth == nil && t.BearerToken == "" {
+		return true
+	}
+	if t.BearerToken != "" {
+		provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(provided), []byte(t.BearerToken)) == 1 {
+			return true
+		}
+	}
+	return t.BasicAuth != nil && t.BasicAuth.check(r)
+}
+
+// findTenant looks up a configured tenant by its name.
+func (c config) findTenant(name string) (TenantConfig, bool) {
+	for _, tenant := range c.Tenants {
+		if tenant.Name == name {
+			return tenant, true
+		}
+	}
+	return TenantConfig{}, false
+}
+
+// tenant returns the configuration of the probes of the tenant, scraping
+// only its targets with its community.
+func (c config) tenant(tenant TenantConfig) config {
+	c.Targets = tenant.Targets
+	if tenant.Community != "" {
+		c.Community = tenant.Community
+	}
+	return c
+}
+
+// tenantProbeHandler serves the metrics of the target passed in the target
+// parameter among those of the tenant in the path. Tenant targets are
+// always scraped on request.
+func tenantProbeHandler(store *configStore, fallback *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := requestLogger(r.Context(), fallback)
+		config := store.Get()
+		tenant, ok := config.findTenant(r.PathValue("tenant"))
+		if !ok {
+			writeError(w, http.StatusNotFound, apiError{Code: errorCodeUnknownTenant, Message: "Tenant not found", Hint: "pass the name of a configured tenant"})
+			return
+		}
+		if !tenant.authorized(r) {
+			if tenant.BasicAuth != nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="wut-temperature-exporter"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="wut-temperature-exporter"`)
+			}
+			writeError(w, http.StatusUnauthorized, apiError{Code: errorCodeUnauthorized, Message: "Unauthorized"})
+			return
+		}
+		serveProbe(w, r, config.tenant(tenant), nil, tenant.Labels, logger.With(zap.String("tenant", tenant.Name)))
+	}
+}
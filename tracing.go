@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TracingConfig exports a span for every scrape via OTLP. The trace IDs of
+// sampled scrapes are attached as exemplars to wut_scrape_duration_seconds
+// and wut_scrape_errors_total. Changes require a restart.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP URL the spans are sent to, e.g.
+	// http://localhost:4318/v1/traces. Tracing is disabled if empty.
+	Endpoint string `mapstructure:"endpoint"`
+	// SampleRatio is the fraction of the scrapes traced, from 0 to 1.
+	// Probes continuing a trace of Prometheus follow its sampling decision.
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// validate checks the tracing configuration for invalid settings.
+func (c TracingConfig) validate() error {
+	if c.Endpoint != "" {
+		u, err := url.Parse(c.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid tracing endpoint %q, must be an http or https URL", c.Endpoint)
+		}
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("invalid tracing sample_ratio %g, must be between 0 and 1", c.SampleRatio)
+	}
+	return nil
+}
+
+// setupTracing installs the global tracer provider used by the collector
+// package if an endpoint is configured. The returned function flushes the
+// pending spans on shutdown.
+func setupTracing(c TracingConfig) (func(context.Context) error, error) {
+	if c.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(c.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", program),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// traceContext continues the traces of requests carrying a W3C trace
+// context, such as the scrapes of a Prometheus with tracing enabled. It
+// does nothing unless tracing is set up.
+func traceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// snmpTrapOID is the varbind carrying the trap identifier in SNMPv2 traps.
+const snmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+
+// trapReceiver converts SNMP traps sent by the devices on alarm into
+// metrics and structured logs.
+type trapReceiver struct {
+	store    *configStore
+	logger   *zap.Logger
+	listener *gosnmp.TrapListener
+}
+
+func newTrapReceiver(store *configStore, logger *zap.Logger) *trapReceiver {
+	r := &trapReceiver{store: store, logger: logger, listener: gosnmp.NewTrapListener()}
+	r.listener.OnNewTrap = r.handle
+	return r
+}
+
+// ListenAndServe receives traps on the given UDP address until Close is
+// called.
+func (r *trapReceiver) ListenAndServe(address string) error {
+	r.logger.Info("Starting SNMP trap listener", zap.String("address", address))
+	return r.listener.Listen(address)
+}
+
+// Close stops the trap listener.
+func (r *trapReceiver) Close() {
+	r.listener.Close()
+}
+
+func (r *trapReceiver) handle(packet *gosnmp.SnmpPacket, addr *net.UDPAddr) {
+	config := r.store.Get()
+	source := addr.IP.String()
+
+	if packet.Version == gosnmp.Version3 {
+		// No USM users are configured, so SNMPv3 traps cannot be
+		// authenticated and their source address could be spoofed.
+		trapsDropped.WithLabelValues("version").Inc()
+		r.logger.Warn("Dropping unauthenticated SNMPv3 trap", zap.String("ip", source))
+		return
+	}
+	if packet.Community != config.Community {
+		trapsDropped.WithLabelValues("community").Inc()
+		r.logger.Warn("Dropping SNMP trap with invalid community", zap.String("ip", source))
+		return
+	}
+	target, ok := config.findTarget(source)
+	if !ok {
+		trapsDropped.WithLabelValues("unknown_target").Inc()
+		r.logger.Warn("Dropping SNMP trap from unknown target", zap.String("ip", source))
+		return
+	}
+
+	alarm := strconv.Itoa(packet.SpecificTrap)
+	fields := []zap.Field{zap.String("ip", source), zap.String("target", target.Name())}
+	for _, variable := range packet.Variables {
+		if variable.Name == snmpTrapOID {
+			if oid, ok := variable.Value.(string); ok {
+				alarm = strconv.Itoa(collector.OIDIndex(oid))
+			}
+			continue
+		}
+		fields = append(fields, zap.String(variable.Name, collector.PDUString(variable)))
+	}
+	fields = append(fields, zap.String("alarm", alarm))
+
+	room := config.collector(target, r.logger).RoomLabel()
+	trapAlarms.WithLabelValues(room, alarm).Inc()
+	trapAlarmTimestamp.WithLabelValues(room, alarm).SetToCurrentTime()
+	r.logger.Info("Received SNMP alarm trap", fields...)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+func TestTrapReceiver(t *testing.T) {
+	config := config{
+		Targets:   []wutconfig.Target{{IP: "192.0.2.1", Room: "Server"}},
+		Community: "public",
+		Options:   wutconfig.DefaultOptions(),
+	}
+	r := newTrapReceiver(newConfigStore(config, zap.NewNop()), zap.NewNop())
+	source := &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 162}
+
+	accepted := testutil.ToFloat64(trapAlarms.WithLabelValues("server", "1"))
+	r.handle(&gosnmp.SnmpPacket{Version: gosnmp.Version1, Community: "public", SnmpTrap: gosnmp.SnmpTrap{SpecificTrap: 1}}, source)
+	if got := testutil.ToFloat64(trapAlarms.WithLabelValues("server", "1")); got != accepted+1 {
+		t.Errorf("expected the SNMPv1 trap to be counted, got %g alarms", got)
+	}
+
+	// A spoofed SNMPv3 trap with the address of a target is rejected.
+	dropped := testutil.ToFloat64(trapsDropped.WithLabelValues("version"))
+	r.handle(&gosnmp.SnmpPacket{Version: gosnmp.Version3, SnmpTrap: gosnmp.SnmpTrap{SpecificTrap: 1}}, source)
+	if got := testutil.ToFloat64(trapsDropped.WithLabelValues("version")); got != dropped+1 {
+		t.Errorf("expected the SNMPv3 trap to be dropped")
+	}
+	if got := testutil.ToFloat64(trapAlarms.WithLabelValues("server", "1")); got != accepted+1 {
+		t.Errorf("expected the SNMPv3 trap not to be counted, got %g alarms", got)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+)
+
+// uiPage is the single page of the web UI. It polls readingsHandler and
+// only uses relative URLs, so it works below --web.route-prefix.
+//
+//go:embed ui/index.html
+var uiPage []byte
+
+// Health states of the targets in readingsHandler.
+const (
+	targetHealthUp      = "up"
+	targetHealthDown    = "down"
+	targetHealthStale   = "stale"
+	targetHealthPending = "pending"
+)
+
+// uiTarget is a target in responses of readingsHandler.
+type uiTarget struct {
+	Name     string      `json:"name"`
+	IP       string      `json:"ip"`
+	Health   string      `json:"health"`
+	Reason   string      `json:"reason,omitempty"`
+	Scraped  *time.Time  `json:"scraped,omitempty"`
+	Readings []uiReading `json:"readings"`
+}
+
+// uiReading is a reading in responses of readingsHandler.
+type uiReading struct {
+	Sensor    string    `json:"sensor"`
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// unitSymbol returns the symbol shown for the unit of a reading.
+func unitSymbol(unit int) string {
+	switch unit {
+	case collector.UnitFahrenheit:
+		return "°F"
+	case collector.UnitKelvin:
+		return "K"
+	case collector.UnitPercentRH:
+		return "%"
+	case collector.UnitHectopascal:
+		return "hPa"
+	case collector.UnitAnalog:
+		return ""
+	}
+	return "°C"
+}
+
+// uiHandler serves the web UI.
+func uiHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(uiPage)
+}
+
+// readingsHandler serves the latest readings and the health of all targets
+// from the results of the background scrapes.
+func (p *poller) readingsHandler(store *configStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		config := store.Get()
+		targets := make([]uiTarget, 0, len(config.Targets))
+		for _, target := range config.Targets {
+			t := uiTarget{Name: target.Name(), IP: target.IP, Health: targetHealthPending, Readings: []uiReading{}}
+			if result, ok := p.Result(target); ok {
+				t.Scraped = &result.Timestamp
+				switch {
+				case result.Err != nil:
+					t.Health, t.Reason = targetHealthDown, collector.Reason(result.Err)
+				case time.Since(result.Timestamp) > 2*target.Interval(config.ScrapeInterval)+scrapeBudget:
+					t.Health = targetHealthStale
+				default:
+					t.Health = targetHealthUp
+				}
+				for _, reading := range result.Readings {
+					t.Readings = append(t.Readings, uiReading{Sensor: reading.Sensor, Value: reading.Value, Unit: unitSymbol(reading.Unit), Timestamp: reading.Timestamp})
+				}
+			}
+			targets = append(targets, t)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(struct {
+			Targets []uiTarget `json:"targets"`
+		}{targets})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, set at build time via
+// -ldflags "-X main.version=... -X main.commit=... -X main.date=...".
+var (
+	version = "dev"
+	commit  = ""
+	date    = ""
+)
+
+func init() {
+	if commit != "" {
+		return
+	}
+	// Fall back to the VCS information embedded by the Go toolchain.
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			commit = setting.Value
+		case "vcs.time":
+			if date == "" {
+				date = setting.Value
+			}
+		}
+	}
+}
+
+// versionInfo describes the running exporter build.
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+func currentVersion() versionInfo {
+	return versionInfo{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+}
+
+func (v versionInfo) String() string {
+	return fmt.Sprintf("wut-temperature-exporter %s (commit %s, built %s, %s)", v.Version, v.Commit, v.Date, v.GoVersion)
+}
+
+// versionHandler serves the build information as JSON.
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(currentVersion())
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// WebConfig configures the HTTP server.
+type WebConfig struct {
+	// ReadHeaderTimeout is the time allowed to read the request headers.
+	ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+	// ReadTimeout is the time allowed to read the complete request.
+	ReadTimeout time.Duration `mapstructure:"read_timeout"`
+	// WriteTimeout is the time allowed to handle a request and write the
+	// response. It has to exceed the duration of the slowest scrape.
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	// IdleTimeout is the time keep-alive connections are kept open
+	// waiting for the next request.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// HTTP2 enables HTTP/2 on TLS connections.
+	HTTP2 bool `mapstructure:"http2"`
+	// H2C enables HTTP/2 without TLS using prior knowledge. It is meant
+	// for clients on trusted networks only.
+	H2C bool `mapstructure:"h2c"`
+	// Compression configures gzip compression of the responses.
+	Compression CompressionConfig `mapstructure:"compression"`
+	// CORS configures cross-origin requests from browser applications.
+	CORS CORSConfig `mapstructure:"cors"`
+	// Listeners are the addresses served, each with its own TLS and
+	// authentication settings. Defaults to a single listener on :9191.
+	Listeners []ListenerConfig `mapstructure:"listeners"`
+	// Routes restrict paths to networks and credentials on all
+	// listeners.
+	Routes []RoutePolicy `mapstructure:"routes"`
+}
+
+// newServer returns the HTTP server of the listener serving the handler
+// with the configured timeouts and protocols.
+func (c WebConfig) newServer(listener ListenerConfig, handler http.Handler) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(c.HTTP2)
+	protocols.SetUnencryptedHTTP2(c.H2C)
+
+	return &http.Server{
+		Protocols:         protocols,
+		Addr:              listener.Address,
+		Handler:           c.Compression.handler(c.CORS.handler(listener.handler(c.routesHandler(handler)))),
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		ReadTimeout:       c.ReadTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+	}
+}
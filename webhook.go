@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// WebhookConfig configures notifications about targets failing background
+// scrapes.
+type WebhookConfig struct {
+	// URL receives the notifications. Disabled if empty.
+	URL string `mapstructure:"url"`
+	// Failures is the number of consecutive failed scrapes after which a
+	// target is reported as down.
+	Failures int `mapstructure:"failures"`
+	// Timeout bounds the delivery of a notification.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// webhookPayload is the JSON body of notifications. Text renders the
+// notification for Slack and Microsoft Teams incoming webhooks, which
+// ignore the other fields.
+type webhookPayload struct {
+	Text      string    `json:"text"`
+	Status    string    `json:"status"`
+	Target    string    `json:"target"`
+	IP        string    `json:"ip"`
+	Failures  int       `json:"failures"`
+	Error     string    `json:"error,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// webhookNotifier posts a notification once a target failed the configured
+// number of consecutive background scrapes, and another one once it
+// recovered.
+type webhookNotifier struct {
+	store  *configStore
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func newWebhookNotifier(store *configStore, logger *zap.Logger) *webhookNotifier {
+	return &webhookNotifier{store: store, logger: logger, failures: make(map[string]int)}
+}
+
+// Observe counts the result of a background scrape of the target.
+func (n *webhookNotifier) Observe(target wutconfig.Target, result collector.Result) {
+	webhook := n.store.Get().Webhook
+	if webhook.URL == "" {
+		return
+	}
+	name := target.Name()
+
+	n.mu.Lock()
+	previous := n.failures[name]
+	if result.Err == nil {
+		delete(n.failures, name)
+	} else {
+		n.failures[name] = previous + 1
+	}
+	n.mu.Unlock()
+
+	payload := webhookPayload{Target: name, IP: target.IP, Timestamp: result.Timestamp}
+	switch {
+	case result.Err != nil && previous+1 == webhook.Failures:
+		payload.Status = "down"
+		payload.Failures = previous + 1
+		payload.Error = result.Err.Error()
+		payload.Reason = collector.Reason(result.Err)
+		payload.Text = fmt.Sprintf("WUT target %s (%s) is down after %d failed scrapes: %s", name, target.IP, payload.Failures, payload.Error)
+	case result.Err == nil && previous >= webhook.Failures:
+		payload.Status = "up"
+		payload.Failures = previous
+		payload.Text = fmt.Sprintf("WUT target %s (%s) recovered after %d failed scrapes", name, target.IP, previous)
+	default:
+		return
+	}
+	go n.send(webhook, payload)
+}
+
+func (n *webhookNotifier) send(webhook WebhookConfig, payload webhookPayload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		n.logger.Error("Error encoding webhook notification", zap.Error(err))
+		return
+	}
+	client := &http.Client{Timeout: webhook.Timeout}
+	resp, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		n.logger.Error("Error sending webhook notification", zap.String("target", payload.Target), zap.Error(err))
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		n.logger.Error("Webhook rejected notification", zap.String("target", payload.Target), zap.Int("status", resp.StatusCode))
+		return
+	}
+	n.logger.Info("Sent webhook notification", zap.String("target", payload.Target), zap.String("status", payload.Status))
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/hm-edu/wut-temperature-exporter/pkg/collector"
+	wutconfig "github.com/hm-edu/wut-temperature-exporter/pkg/config"
+)
+
+// maxDiscoveryAddresses bounds the size of the subnets scanned by init.
+const maxDiscoveryAddresses = 4096
+
+// initFlags are the flags of the init command.
+type initFlags struct {
+	output    *string
+	force     *bool
+	subnet    *string
+	community *string
+	port      *int
+	timeout   *time.Duration
+}
+
+// newInitFlags defines the flags of the init command on the flag set.
+func newInitFlags(flags *pflag.FlagSet) initFlags {
+	return initFlags{
+		output:    flags.String("output", "config.yaml", "Configuration file to write"),
+		force:     flags.Bool("force", false, "Overwrite an existing configuration file"),
+		subnet:    flags.String("subnet", "", "Subnet to scan in CIDR notation, asked for if not given"),
+		community: flags.String("community", "", "SNMP community of the devices, asked for if not given"),
+		port:      flags.Int("port", 161, "SNMP port of the devices"),
+		timeout:   flags.Duration("timeout", 2*time.Second, "Time to wait for the answer of every address"),
+	}
+}
+
+// runInit discovers the W&T devices on a subnet, asks for their rooms and
+// writes a configuration file.
+func runInit(args []string) int {
+	flags := pflag.NewFlagSet("init", pflag.ContinueOnError)
+	f := newInitFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if _, err := os.Stat(*f.output); err == nil && !*f.force {
+		fmt.Fprintf(os.Stderr, "%s exists, pass --force to overwrite it\n", *f.output)
+		return 1
+	}
+
+	p := newPrompter(os.Stdin, os.Stdout)
+	if *f.subnet == "" {
+		*f.subnet = p.ask("Subnet to scan for devices, e.g. 192.168.10.0/24", "")
+	}
+	prefix, err := netip.ParsePrefix(*f.subnet)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid subnet: %v\n", err)
+		return 1
+	}
+	if *f.community == "" {
+		*f.community = p.ask("SNMP community", "public")
+	}
+
+	fmt.Fprintf(os.Stdout, "Scanning %s...\n", prefix)
+	devices, err := discover(context.Background(), prefix, *f.port, *f.community, *f.timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(devices) == 0 {
+		fmt.Fprintln(os.Stderr, "No W&T devices found, check the subnet, community and that SNMP is enabled on the devices")
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "Found %d W&T devices.\n", len(devices))
+
+	data, err := wizardConfig{Community: *f.community, Targets: p.rooms(devices)}.render()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*f.output, data, 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stdout, "Wrote %s.\n", *f.output)
+	return 0
+}
+
+// discoveredDevice is a W&T device found by discover.
+type discoveredDevice struct {
+	address  string
+	identity collector.Identity
+}
+
+// discover queries the system group of all addresses of the subnet in
+// parallel and returns the W&T devices ordered by address.
+func discover(ctx context.Context, prefix netip.Prefix, port int, community string, timeout time.Duration) ([]discoveredDevice, error) {
+	addresses, err := hostAddresses(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		mu      sync.Mutex
+		devices []discoveredDevice
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, 64)
+	for _, addr := range addresses {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			address := addr.String()
+			if port != 161 {
+				address = net.JoinHostPort(address, strconv.Itoa(port))
+			}
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			c := collector.New(wutconfig.Target{IP: address}, community, wutconfig.DefaultOptions(), zap.NewNop())
+			identity, err := c.Identify(ctx)
+			if err != nil || !identity.IsWUT() {
+				return
+			}
+			mu.Lock()
+			devices = append(devices, discoveredDevice{address: address, identity: identity})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	slices.SortFunc(devices, func(a, b discoveredDevice) int {
+		return netip.MustParseAddrPort(withPort(a.address)).Compare(netip.MustParseAddrPort(withPort(b.address)))
+	})
+	return devices, nil
+}
+
+// withPort adds the default SNMP port to addresses without one.
+func withPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, "161")
+}
+
+// hostAddresses returns the host addresses of the subnet, leaving out the
+// network and broadcast address of IPv4 subnets.
+func hostAddresses(prefix netip.Prefix) ([]netip.Addr, error) {
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 12 {
+		return nil, fmt.Errorf("subnet %s is too large, scan at most %d addresses", prefix, maxDiscoveryAddresses)
+	}
+	var addresses []netip.Addr
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		addresses = append(addresses, addr)
+	}
+	if prefix.Addr().Is4() && hostBits >= 2 {
+		addresses = addresses[1 : len(addresses)-1]
+	}
+	return addresses, nil
+}
+
+// prompter asks the operator for values on the terminal.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewScanner(in), out: out}
+}
+
+// ask returns the answer to the question, or the default if the answer is
+// empty.
+func (p *prompter) ask(question, fallback string) string {
+	if fallback != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, fallback)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	if !p.in.Scan() {
+		return fallback
+	}
+	if answer := strings.TrimSpace(p.in.Text()); answer != "" {
+		return answer
+	}
+	return fallback
+}
+
+// rooms asks for the room of every device, suggesting its configured
+// location or name. Devices answered with "-" are left out.
+func (p *prompter) rooms(devices []discoveredDevice) []wizardTarget {
+	var targets []wizardTarget
+	for _, device := range devices {
+		fmt.Fprintf(p.out, "%s: %s\n", device.address, device.identity.Description)
+		suggestion := device.identity.Location
+		if suggestion == "" {
+			suggestion = device.identity.Name
+		}
+		room := p.ask(`  Room (or "-" to skip)`, suggestion)
+		if room == "-" {
+			continue
+		}
+		targets = append(targets, wizardTarget{IP: device.address, Room: room})
+	}
+	return targets
+}
+
+// wizardConfig is the configuration file written by init.
+type wizardConfig struct {
+	Community string         `yaml:"community"`
+	Targets   []wizardTarget `yaml:"targets"`
+}
+
+type wizardTarget struct {
+	IP   string `yaml:"ip"`
+	Room string `yaml:"room,omitempty"`
+}
+
+// render returns the configuration file, validated like on startup.
+func (c wizardConfig) render() ([]byte, error) {
+	if len(c.Targets) == 0 {
+		return nil, errors.New("no targets selected")
+	}
+	var data bytes.Buffer
+	data.WriteString("# Written by wut-temperature-exporter init. See config.yaml of the\n# exporter for all options.\n")
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c); err != nil {
+		return nil, err
+	}
+
+	setupConfig()
+	if err := viper.ReadConfig(bytes.NewReader(data.Bytes())); err != nil {
+		return nil, err
+	}
+	var parsed config
+	if err := viper.Unmarshal(&parsed); err != nil {
+		return nil, err
+	}
+	if err := parsed.validate(); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
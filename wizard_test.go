@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestWizardDiscoversDevices(t *testing.T) {
+	t.Cleanup(viper.Reset)
+	agent := startAgent(t, "public").Targets[0].IP
+	_, port, _ := net.SplitHostPort(agent)
+	p, _ := strconv.Atoi(port)
+
+	devices, err := discover(t.Context(), netip.MustParsePrefix("127.0.0.1/32"), p, "public", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 || devices[0].address != agent || devices[0].identity.Description != "Web-Thermometer NTC 57613" {
+		t.Fatalf("expected the agent at %s, got %+v", agent, devices)
+	}
+
+	var out strings.Builder
+	targets := newPrompter(strings.NewReader("Server room\n"), &out).rooms(devices)
+	data, err := wizardConfig{Community: "public", Targets: targets}.render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "community: public\ntargets:\n  - ip: " + agent + "\n    room: Server room\n"
+	if !strings.HasSuffix(string(data), expected) {
+		t.Errorf("expected config ending with\n%s\ngot\n%s", expected, data)
+	}
+}